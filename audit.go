@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+)
+
+const (
+	// auditFile is where every change the bot makes on github is logged, one JSON entry per line
+	auditFile = "./audit.log"
+	// creatorStoreFile is where issues are mapped to the slack users who filed them through the bot
+	creatorStoreFile = "./creators"
+)
+
+// auditEntry is a line in the audit log.
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Action string    `json:"action"`
+	Issue  string    `json:"issue"`
+	Note   string    `json:"note,omitempty"`
+}
+
+// auditLog appends entries to auditFile.
+type auditLog struct {
+	mu sync.Mutex
+}
+
+// write appends an entry to the audit log.
+func (a *auditLog) write(entry auditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	f, err := os.OpenFile(auditFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return trace.Wrap(err)
+}
+
+// audit records that a slack user changed an issue through the bot.
+func (s *SlackBot) audit(user, action string, ref issueRef, note string) {
+	entry := auditEntry{Time: time.Now().UTC(), User: user, Action: action, Issue: ref.String(), Note: note}
+	if err := s.auditLog.write(entry); err != nil {
+		log.Errorf("couldn't write audit log: %v", trace.DebugReport(err))
+	}
+}
+
+// recordIssue remembers who filed an issue through the bot, so they can edit and undo it, and if it was
+// filed from a thread, links the thread to it. The issue is then added to its project, if it has one.
+func (s *SlackBot) recordIssue(user, channel, threadTS string, issue *Issue) {
+	ref := issue.Ref()
+	if err := s.creators.Put(ref.String(), user); err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	s.linkThread(channel, threadTS, ref)
+	s.recent.add(ref, user)
+	s.audit(user, "create", ref, "")
+	go s.addToProject(user, channel, issue)
+}
+
+// createdBy reports whether user filed an issue through the bot.
+func (s *SlackBot) createdBy(ref issueRef, user string) bool {
+	var creator string
+	ok, err := s.creators.Get(ref.String(), &creator)
+	if err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	return ok && creator == user
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/shomali11/proper"
+)
+
+const (
+	// MaxBatchIssues is the most issues new-batch files at once
+	MaxBatchIssues = 50
+)
+
+var (
+	// batchRegex matches "new-batch <repo> [options]" followed by the list on the next lines
+	batchRegex = regexp.MustCompile(`(?s)^\s*(?:<@(\S+)>)?\s*new-batch\s+"?([^\s"]+)"?([^\n]*)\n(.*)$`)
+	// batchOptionRegex finds key=value options, where the value can be quoted
+	batchOptionRegex = regexp.MustCompile(`(\w+)=(?:"([^"]*)"|(\S+))`)
+	// listItemRegex finds a bulleted, numbered or checklist item
+	listItemRegex = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+(?:\[[ xX]\]\s+)?(.+?)\s*$`)
+	// ErrBatchUsage is reported when new-batch isn't given a list
+	ErrBatchUsage = errors.New("Put the list on the lines after new-batch repo, eg:\nnew-batch teleport labels=planning milestone=\"v9\" tracking=\"Q3 plan\"\n- first issue\n- second issue")
+)
+
+// batchParser matches "new-batch", its options and its list.
+func (s *SlackBot) batchParser(text string) (*proper.Properties, bool) {
+	resultSlice := batchRegex.FindStringSubmatch(text)
+	if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
+		return nil, false
+	}
+	return proper.NewProperties(map[string]string{
+		"repo":    resultSlice[2],
+		"options": resultSlice[3],
+		"list":    resultSlice[4],
+	}), true
+}
+
+// batchOptions parses the labels, milestone and tracking issue title options of new-batch.
+func batchOptions(options string) (fields IssueFields, tracking string, err error) {
+	for _, match := range batchOptionRegex.FindAllStringSubmatch(options, -1) {
+		value := match[2] + match[3]
+		switch strings.ToLower(match[1]) {
+		case "labels", "label":
+			fields.Labels = append(fields.Labels, splitList(value)...)
+		case "milestone":
+			fields.Milestone = value
+		case "tracking":
+			tracking = value
+		default:
+			return fields, "", fmt.Errorf("new-batch doesn't have a %v option, try labels, milestone or tracking", match[1])
+		}
+	}
+	return fields, tracking, nil
+}
+
+// listItems finds the items of a bulleted, numbered or checklist list. Other lines are skipped.
+func listItems(list string) []string {
+	var items []string
+	for _, line := range strings.Split(list, "\n") {
+		if match := listItemRegex.FindStringSubmatch(line); match != nil {
+			items = append(items, match[1])
+		}
+	}
+	return items
+}
+
+// batchResult is how filing one list item went.
+type batchResult struct {
+	title string
+	issue *Issue
+	err   error
+}
+
+// createBatch is the callback for "new-batch", which files an issue for each item in a list.
+func (s *SlackBot) createBatch(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	repo := r.StringParam("repo", "")
+	if !strings.Contains(repo, "/") {
+		repo = s.DefaultOwner(event.Channel) + "/" + repo
+	}
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		w.ReportError(ErrBadRepo)
+		return
+	}
+	// A whole list can't be previewed one issue at a time, so repos that ask for previews are left alone
+	if s.settings.repo(repo).Confirm {
+		w.ReportError(fmt.Errorf("%v asks to preview every new issue, so new-batch can't file in it. Use new for each one", repo))
+		return
+	}
+	fields, tracking, err := batchOptions(r.StringParam("options", ""))
+	if err != nil {
+		w.ReportError(err)
+		return
+	}
+	items := listItems(r.StringParam("list", ""))
+	if len(items) == 0 {
+		w.ReportError(ErrBatchUsage)
+		return
+	}
+	if len(items) > MaxBatchIssues {
+		w.ReportError(fmt.Errorf("That's %v issues, new-batch files up to %v at once", len(items), MaxBatchIssues))
+		return
+	}
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+
+	// Check the shared fields once, rather than failing on every item
+	checkCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	if _, err := client.labelIDs(checkCtx, owner, name, fields.Labels); err != nil {
+		w.ReportError(fmt.Errorf("Couldn't use those labels: %v", trace.UserMessage(err)))
+		return
+	}
+	if len(fields.Milestone) != 0 {
+		if _, err := client.milestoneID(checkCtx, owner, name, fields.Milestone); err != nil {
+			w.ReportError(fmt.Errorf("Couldn't use that milestone: %v", trace.UserMessage(err)))
+			return
+		}
+	}
+
+	footer := s.commandAttribution(checkCtx, r, "Filed")
+	var results []batchResult
+	var tasks []string
+	for _, item := range items {
+		title := s.directory.ToGitHub(item)
+		subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+		issue, err := client.NewIssue(subCtx, repo, title, footer, fields)
+		cancel()
+		if err != nil {
+			log.Infof("batch issue error: %v", trace.DebugReport(err))
+		} else {
+			s.recordIssue(event.User, event.Channel, "", issue)
+			tasks = append(tasks, fmt.Sprintf("- [ ] #%v", issue.Number))
+		}
+		results = append(results, batchResult{title: title, issue: issue, err: err})
+	}
+
+	summary := batchSummary(results)
+	if len(tracking) != 0 && len(tasks) != 0 {
+		subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+		body := strings.Join(tasks, "\n") + "\n\n" + footer
+		parent, err := client.NewIssue(subCtx, repo, s.directory.ToGitHub(tracking), body, fields)
+		cancel()
+		if err != nil {
+			log.Infof("tracking issue error: %v", trace.DebugReport(err))
+			summary += fmt.Sprintf("\nCouldn't file the tracking issue: %v", trace.UserMessage(err))
+		} else {
+			s.recordIssue(event.User, event.Channel, event.ThreadTimestamp, parent)
+			summary += fmt.Sprintf("\nTracking issue: %v", parent.Url)
+		}
+	}
+	s.replyInThread(r, w, summary)
+}
+
+// batchSummary is a table of how filing each item went.
+func batchSummary(results []batchResult) string {
+	var table strings.Builder
+	filed := 0
+	writer := tabwriter.NewWriter(&table, 0, 4, 2, ' ', 0)
+	for _, result := range results {
+		if result.err != nil {
+			fmt.Fprintf(writer, "✗\t%v\t%v\n", excerptTitle(result.title), trace.UserMessage(result.err))
+			continue
+		}
+		filed++
+		fmt.Fprintf(writer, "✓\t%v\t#%v\n", excerptTitle(result.title), result.issue.Number)
+	}
+	writer.Flush()
+	return fmt.Sprintf("Filed %v of %v issues\n```%v```", filed, len(results), table.String())
+}
+
+// excerptTitle shortens a title for the summary table.
+func excerptTitle(title string) string {
+	if runes := []rune(title); len(runes) > 50 {
+		return string(runes[:49]) + "…"
+	}
+	return title
+}
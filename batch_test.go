@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/nlopes/slack"
+	. "gopkg.in/check.v1"
+)
+
+type BatchSuite struct{}
+
+var _ = Suite(&BatchSuite{})
+
+func (s *BatchSuite) TestBatchParser(c *C) {
+	bot := &SlackBot{botID: "UBOT"}
+	properties, ok := bot.batchParser("<@UBOT> new-batch gravitational/teleport labels=planning,ui tracking=\"Q3 plan\"\n- one\n- two")
+	c.Assert(ok, Equals, true)
+	c.Assert(properties.StringParam("repo", ""), Equals, "gravitational/teleport")
+	c.Assert(properties.StringParam("list", ""), Equals, "- one\n- two")
+
+	fields, tracking, err := batchOptions(properties.StringParam("options", ""))
+	c.Assert(err, IsNil)
+	c.Assert(fields.Labels, DeepEquals, []string{"planning", "ui"})
+	c.Assert(tracking, Equals, "Q3 plan")
+
+	_, _, err = batchOptions(" assignee=aj")
+	c.Assert(err, NotNil)
+	_, ok = bot.batchParser("new-batch teleport")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *BatchSuite) TestListItems(c *C) {
+	list := "Planning notes:\n- first\n* second \n3. third\n4) fourth\n- [ ] fifth\n- [x] sixth\nnot an item\n-not an item either"
+	c.Assert(listItems(list), DeepEquals, []string{"first", "second", "third", "fourth", "fifth", "sixth"})
+}
+
+func (s *BatchSuite) TestBatchSummary(c *C) {
+	summary := batchSummary([]batchResult{
+		{title: "first", issue: &Issue{Number: 12}},
+		{title: "a much longer second", err: errors.New("rate limited")},
+	})
+	c.Assert(summary, Equals, "Filed 1 of 2 issues\n```"+
+		"✓  first                 #12\n"+
+		"✗  a much longer second  rate limited\n```")
+}
+
+func (s *BatchSuite) TestBatchRefusesConfirmRepos(c *C) {
+	bot := &SlackBot{running: true, wg: &sync.WaitGroup{}, settings: settings{Repos: map[string]repoSettings{"gravitational/teleport": {Confirm: true}}}}
+	r := &testRequest{
+		event:  slack.MessageEvent{Msg: slack.Msg{User: "U1", Channel: "C1"}},
+		params: map[string]string{"repo": "Gravitational/Teleport", "list": "- one\n- two"},
+	}
+	w := &testResponder{}
+	bot.createBatch(r, w)
+	c.Assert(w.errors, HasLen, 1)
+	c.Assert(w.errors[0], ErrorMatches, ".*asks to preview every new issue.*")
+}
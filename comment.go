@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/shomali11/proper"
+)
+
+var (
+	// commentRegex matches an optional issue followed by a quoted comment, see issueRegex
+	commentRegex = regexp.MustCompile(`^\s*(?:<@(\S+)>)?\s*comment\s+(?:([^\s"]+)\s+)?"([^"\\]*(?:\\.[^"\\]*)*)"\s*$`)
+	// ErrNoIssue is reported when a command doesn't name an issue and isn't in a thread linked to one
+	ErrNoIssue = errors.New("Name an issue, eg gravitational/teleport#4521, or reply in a thread that was filed as an issue")
+)
+
+// commentParser matches `comment owner/repo#N "text"`, or `comment "text"` in a thread linked to an issue.
+func (s *SlackBot) commentParser(text string) (*proper.Properties, bool) {
+	resultSlice := commentRegex.FindStringSubmatch(text)
+	if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
+		return nil, false
+	}
+	if len(resultSlice[3]) == 0 {
+		return nil, false
+	}
+	parameters := make(map[string]string)
+	parameters["ref"] = resultSlice[2]
+	parameters["text"] = escapeRegex.ReplaceAllString(resultSlice[3], "$1")
+	return proper.NewProperties(parameters), true
+}
+
+// commandIssue finds the issue a command is about: the one it names, or else the one its thread is linked to.
+func (s *SlackBot) commandIssue(r request) (issueRef, bool) {
+	event := r.Event()
+	if ref := r.StringParam("ref", ""); len(ref) != 0 {
+		return parseIssueRef(ref, s.DefaultOwner(event.Channel))
+	}
+	return s.threadIssue(event.Channel, event.ThreadTimestamp)
+}
+
+// addComment is the callback for the "comment" command.
+func (s *SlackBot) addComment(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	ref, ok := s.commandIssue(r)
+	if !ok {
+		w.ReportError(ErrNoIssue)
+		return
+	}
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	body := s.toGitHub(r.StringParam("text", "")) + "\n\n" + s.commandAttribution(subCtx, r, "Commented")
+	url, err := client.AddComment(subCtx, ref, body)
+	if err != nil {
+		w.ReportError(fmt.Errorf("Couldn't comment on %v", ref))
+		log.Infof("comment error: %v", trace.DebugReport(err))
+		return
+	}
+	s.audit(event.User, "comment", ref, url)
+	s.replyInThread(r, w, url)
+}
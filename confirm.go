@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+const (
+	// editPendingActionID identifies the "Edit" button on a preview
+	editPendingActionID = "pending_edit"
+	// maxPreviewLength is how much of a body fits in a slack section
+	maxPreviewLength = 2900
+)
+
+// previewIssue shows the user exactly what will be filed, with buttons to submit, edit or cancel it,
+// if the repo's settings ask for confirmation. It returns true if the issue now waits for a button.
+func (s *SlackBot) previewIssue(r request, w responder, issue pendingIssue) bool {
+	if !s.settings.repo(issue.Repo).Confirm {
+		return false
+	}
+	event := r.Event()
+	issue.User, issue.Channel, issue.Thread = event.User, event.Channel, event.ThreadTimestamp
+	id := s.pending.add(issue)
+
+	mrkdwn := func(text string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.MarkdownType, text, false, false)
+	}
+	button := func(actionID, text string) *slack.ButtonBlockElement {
+		return slack.NewButtonBlockElement(actionID, id, slack.NewTextBlockObject(slack.PlainTextType, text, false, false))
+	}
+	body := issue.Body
+	if runes := []rune(body); len(runes) > maxPreviewLength {
+		body = string(runes[:maxPreviewLength-1]) + "…"
+	}
+	if len(strings.TrimSpace(body)) == 0 {
+		body = " "
+	}
+	text := fmt.Sprintf("This will be filed in %v, submit it within %v minutes:\n*%v*", issue.Repo, PendingIssueMinutes, issue.Title)
+	submit := button(fileAnywayActionID, "Submit")
+	submit.Style = slack.StylePrimary
+	blocks := []slack.Block{
+		slack.NewSectionBlock(mrkdwn(text), []*slack.TextBlockObject{
+			mrkdwn("*Repo*\n" + issue.Repo),
+			mrkdwn("*Labels*\n" + listOrNone(issue.Fields.Labels)),
+		}, nil),
+		slack.NewSectionBlock(mrkdwn("```"+body+"```"), nil, nil),
+		slack.NewActionBlock("", submit, button(editPendingActionID, "Edit"), button(cancelPendingActionID, "Cancel")),
+	}
+
+	options := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(blocks...),
+	}
+	if len(event.ThreadTimestamp) != 0 {
+		options = append(options, slack.MsgOptionTS(event.ThreadTimestamp))
+	}
+	if _, err := s.sBot.Client().PostEphemeral(event.Channel, event.User, options...); err != nil {
+		// Eg a slash command in a channel the bot isn't in, where the buttons can't be shown
+		log.Infof("couldn't post preview: %v", err)
+		s.pending.take(id, event.User)
+		w.Reply(fmt.Sprintf("%v needs you to confirm new issues, use `new` from a channel the bot is in", issue.Repo))
+	}
+	return true
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+	"github.com/shomali11/proper"
+)
+
+const (
+	// ConversationIdleMinutes is how long a guided new issue waits for an answer before it's given up on
+	ConversationIdleMinutes = 15
+	// MaxRecentRepos is how many repos a guided new issue offers
+	MaxRecentRepos = 5
+)
+
+// The questions of a guided new issue, in the order they're asked
+const (
+	stepRepo = iota
+	stepTitle
+	stepDescription
+	stepReproduce
+	stepSeverity
+	stepConfirm
+)
+
+var (
+	// conversationRegex matches "new" on its own
+	conversationRegex = regexp.MustCompile(`^\s*(?:<@(\S+)>)?\s*new\s*$`)
+	// severities are the answers to how severe an issue is, least first
+	severities = []string{"low", "medium", "high", "critical"}
+	// ErrConfirm is reported when the last answer isn't file, back or cancel
+	ErrConfirm = errors.New("Say `file` to file it, `back` to change something, or `cancel`")
+)
+
+// conversation is a guided new issue, asked one question at a time in a DM.
+type conversation struct {
+	mu      sync.Mutex
+	channel string // the DM
+	origin  string // where "new" was said, for the default owner
+	step    int
+	repos   []string // the repos offered
+	timer   *time.Timer
+
+	repo        string
+	title       string
+	description string
+	reproduce   string
+	severity    string
+}
+
+// conversations are the guided new issues in progress, by slack user.
+type conversations struct {
+	mu     sync.Mutex
+	byUser map[string]*conversation
+}
+
+// start begins a user's conversation, replacing any they had. idle is called if it isn't answered in time.
+func (c *conversations) start(user string, conv *conversation, idle func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byUser == nil {
+		c.byUser = make(map[string]*conversation)
+	}
+	if old, ok := c.byUser[user]; ok {
+		old.timer.Stop()
+	}
+	conv.timer = time.AfterFunc(time.Minute*ConversationIdleMinutes, func() {
+		if c.end(user, conv) {
+			idle()
+		}
+	})
+	c.byUser[user] = conv
+}
+
+// get returns the user's conversation if it's in channel.
+func (c *conversations) get(user, channel string) (*conversation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conv, ok := c.byUser[user]
+	if !ok || conv.channel != channel {
+		return nil, false
+	}
+	return conv, true
+}
+
+// end forgets the user's conversation if it's still conv, and reports whether it was.
+func (c *conversations) end(user string, conv *conversation) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byUser[user] != conv {
+		return false
+	}
+	conv.timer.Stop()
+	delete(c.byUser, user)
+	return true
+}
+
+// conversationParser matches "new" without any arguments.
+func (s *SlackBot) conversationParser(text string) (*proper.Properties, bool) {
+	resultSlice := conversationRegex.FindStringSubmatch(text)
+	if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
+		return nil, false
+	}
+	return proper.NewProperties(map[string]string{}), true
+}
+
+// startConversation is the callback for "new" on its own, which asks for the issue one question at a time in a DM.
+func (s *SlackBot) startConversation(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	if !s.CheckClient(w, s.GetGBot(r)) {
+		return
+	}
+	api := s.sBot.Client()
+	channel := event.Channel
+	if !strings.HasPrefix(channel, "D") {
+		_, _, dm, err := api.OpenIMChannel(event.User)
+		if err != nil {
+			w.ReportError(errors.New("Couldn't DM you"))
+			log.Infof("couldn't open DM: %v", trace.DebugReport(err))
+			return
+		}
+		channel = dm
+		w.Reply("I've sent you a DM to ask about the issue")
+	}
+	conv := &conversation{channel: channel, origin: event.Channel, repos: s.recentRepos(event.User, event.Channel)}
+	s.guided.start(event.User, conv, func() {
+		s.say(channel, fmt.Sprintf("I stopped waiting after %v minutes and didn't file anything. Say `new` to start again", ConversationIdleMinutes))
+	})
+	s.say(channel, conv.question()+"\n_Say `back` to change your last answer, or `cancel` at any time_")
+}
+
+// say posts a message to a channel.
+func (s *SlackBot) say(channel, text string) {
+	if _, _, err := s.sBot.Client().PostMessage(channel, slack.MsgOptionText(text, false)); err != nil {
+		log.Errorf("couldn't post to %v: %v", channel, trace.DebugReport(err))
+	}
+}
+
+// recentRepos are the repos to offer: the channel's, then the ones the user has filed the most issues in.
+func (s *SlackBot) recentRepos(user, channel string) []string {
+	counts := make(map[string]int)
+	for _, key := range s.creators.Keys() {
+		var creator string
+		if ok, err := s.creators.Get(key, &creator); !ok || err != nil || creator != user {
+			continue
+		}
+		if ref, ok := parseIssueRef(key, ""); ok {
+			counts[ref.Owner+"/"+ref.Repo]++
+		}
+	}
+	var repos []string
+	for repo := range counts {
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		if counts[repos[i]] != counts[repos[j]] {
+			return counts[repos[i]] > counts[repos[j]]
+		}
+		return repos[i] < repos[j]
+	})
+	if repo := s.settings.Channels[channel].Repo; len(repo) != 0 {
+		if !strings.Contains(repo, "/") {
+			repo = s.DefaultOwner(channel) + "/" + repo
+		}
+		for i, recent := range repos {
+			if recent == repo {
+				repos = append(repos[:i], repos[i+1:]...)
+				break
+			}
+		}
+		repos = append([]string{repo}, repos...)
+	}
+	if len(repos) > MaxRecentRepos {
+		repos = repos[:MaxRecentRepos]
+	}
+	return repos
+}
+
+// question is what the conversation asks next.
+func (c *conversation) question() string {
+	switch c.step {
+	case stepRepo:
+		if len(c.repos) == 0 {
+			return "Which repo is it for?"
+		}
+		lines := []string{"Which repo is it for? Say its name, or the number of one of these:"}
+		for i, repo := range c.repos {
+			lines = append(lines, fmt.Sprintf("%v. %v", i+1, repo))
+		}
+		return strings.Join(lines, "\n")
+	case stepTitle:
+		return "What's its title?"
+	case stepDescription:
+		return "Describe the problem, or say `skip`"
+	case stepReproduce:
+		return "What are the steps to reproduce it? Or say `skip`"
+	case stepSeverity:
+		return "How severe is it? " + codeList(severities)
+	}
+	return fmt.Sprintf("I'll file *%v* in %v, with %v severity:\n```%v```\n%v",
+		c.title, c.repo, c.severity, excerpt(conversationBody(c.description, c.reproduce, c.severity)), ErrConfirm)
+}
+
+// answer takes the answer to the current question.
+func (c *conversation) answer(text, defaultOwner string) error {
+	skip := strings.EqualFold(text, "skip")
+	switch c.step {
+	case stepRepo:
+		if n, err := strconv.Atoi(text); err == nil {
+			if n < 1 || n > len(c.repos) {
+				return fmt.Errorf("Pick a number from 1 to %v, or say the repo's name", len(c.repos))
+			}
+			c.repo = c.repos[n-1]
+			return nil
+		}
+		repo := strings.Trim(text, "\"`")
+		if !strings.Contains(repo, "/") {
+			repo = defaultOwner + "/" + repo
+		}
+		if _, _, err := splitRepo(repo); err != nil || strings.ContainsAny(repo, " \n") {
+			return errors.New("That isn't a repo, say it like owner/repo")
+		}
+		c.repo = repo
+	case stepTitle:
+		if len(text) == 0 || skip {
+			return errors.New("An issue needs a title")
+		}
+		c.title = text
+	case stepDescription:
+		if skip {
+			text = ""
+		}
+		c.description = text
+	case stepReproduce:
+		if skip {
+			text = ""
+		}
+		c.reproduce = text
+	case stepSeverity:
+		severity := strings.ToLower(text)
+		if n, err := strconv.Atoi(text); err == nil && n >= 1 && n <= len(severities) {
+			severity = severities[n-1]
+		}
+		for _, known := range severities {
+			if severity == known {
+				c.severity = severity
+				return nil
+			}
+		}
+		return fmt.Errorf("Say one of %v", codeList(severities))
+	case stepConfirm:
+		switch strings.ToLower(text) {
+		case "file", "yes", "y":
+		default:
+			return ErrConfirm
+		}
+	}
+	return nil
+}
+
+// conversationBody is the body of an issue from a guided conversation.
+func conversationBody(description, reproduce, severity string) string {
+	var parts []string
+	if len(description) != 0 {
+		parts = append(parts, description)
+	}
+	if len(reproduce) != 0 {
+		parts = append(parts, "### Steps to reproduce\n\n"+reproduce)
+	}
+	return strings.Join(append(parts, "### Severity\n\n"+severity), "\n\n")
+}
+
+// converse takes a message in a user's guided new issue as the answer to its question, and reports
+// whether it was one. Answers aren't taken as commands, even if they look like one.
+func (s *SlackBot) converse(r request, w responder) bool {
+	event := r.Event()
+	conv, ok := s.guided.get(event.User, event.Channel)
+	if !ok {
+		return false
+	}
+	conv.mu.Lock()
+	defer conv.mu.Unlock()
+	conv.timer.Reset(time.Minute * ConversationIdleMinutes)
+	text := strings.TrimSpace(event.Text)
+	switch strings.ToLower(text) {
+	case "cancel":
+		s.guided.end(event.User, conv)
+		w.Reply("OK, I didn't file anything")
+		return true
+	case "back":
+		if conv.step > stepRepo {
+			conv.step--
+		}
+		w.Reply(conv.question())
+		return true
+	}
+	if err := conv.answer(text, s.DefaultOwner(conv.origin)); err != nil {
+		w.ReportError(err)
+		return true
+	}
+	if conv.step < stepConfirm {
+		conv.step++
+		w.Reply(conv.question())
+		return true
+	}
+	if s.guided.end(event.User, conv) {
+		s.fileConversation(r, w, conv)
+	}
+	return true
+}
+
+// fileConversation files the issue from a finished conversation, through the repo's preview and duplicate checks.
+func (s *SlackBot) fileConversation(r request, w responder, conv *conversation) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+	title := s.directory.ToGitHub(conv.title)
+	body := conversationBody(s.toGitHub(conv.description), s.toGitHub(conv.reproduce), conv.severity)
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	body = withAttribution(body, s.userAttribution(subCtx, event.User, "Filed"))
+	pending := pendingIssue{Repo: conv.repo, Title: title, Body: body}
+	if s.previewIssue(r, w, pending) || s.checkDuplicates(subCtx, r, w, client, pending) {
+		return
+	}
+	issue, err := client.NewIssue(subCtx, conv.repo, title, body, IssueFields{})
+	if err != nil {
+		w.ReportError(errors.New("There was an error with the GitHub interface... Check 1) the repo name 2) the logs"))
+		log.Infof("conversation issue error: %v", trace.DebugReport(err))
+		return
+	}
+	s.recordIssue(event.User, event.Channel, "", issue)
+	s.postNewIssue(r, w, issue)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ConversationSuite struct{}
+
+var _ = Suite(&ConversationSuite{})
+
+func (s *ConversationSuite) TestConversationParser(c *C) {
+	bot := &SlackBot{botID: "UBOT"}
+	_, ok := bot.conversationParser("<@UBOT> new")
+	c.Assert(ok, Equals, true)
+	_, ok = bot.conversationParser("new ")
+	c.Assert(ok, Equals, true)
+	_, ok = bot.conversationParser(`new "teleport" "title"`)
+	c.Assert(ok, Equals, false)
+	_, ok = bot.conversationParser("<@UOTHER> new")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *ConversationSuite) TestAnswers(c *C) {
+	conv := &conversation{repos: []string{"gravitational/teleport", "gravitational/docs"}}
+	steps := []struct {
+		answer string
+		ok     bool
+	}{
+		{answer: "3", ok: false},
+		{answer: "2", ok: true},
+		{answer: "skip", ok: false},
+		{answer: "tsh login hangs", ok: true},
+		{answer: "It never returns", ok: true},
+		{answer: "skip", ok: true},
+		{answer: "urgent", ok: false},
+		{answer: "3", ok: true},
+		{answer: "maybe", ok: false},
+		{answer: "file", ok: true},
+	}
+	for i, step := range steps {
+		err := conv.answer(step.answer, "gravitational")
+		c.Assert(err == nil, Equals, step.ok, Commentf("step %v: %q", i, step.answer))
+		if err == nil && conv.step < stepConfirm {
+			conv.step++
+		}
+	}
+	c.Assert(conv.repo, Equals, "gravitational/docs")
+	c.Assert(conv.title, Equals, "tsh login hangs")
+	c.Assert(conv.reproduce, Equals, "")
+	c.Assert(conv.severity, Equals, "high")
+	c.Assert(conversationBody(conv.description, conv.reproduce, conv.severity), Equals,
+		"It never returns\n\n### Severity\n\nhigh")
+
+	conv = &conversation{}
+	c.Assert(conv.answer("teleport", "gravitational"), IsNil)
+	c.Assert(conv.repo, Equals, "gravitational/teleport")
+	c.Assert(conv.answer("not a repo", "gravitational"), NotNil)
+}
+
+func (s *ConversationSuite) TestEnd(c *C) {
+	var guided conversations
+	first, second := &conversation{channel: "D1"}, &conversation{channel: "D1"}
+	guided.start("U1", first, func() {})
+	guided.start("U1", second, func() {})
+	_, ok := guided.get("U1", "C1")
+	c.Assert(ok, Equals, false)
+	conv, ok := guided.get("U1", "D1")
+	c.Assert(ok, Equals, true)
+	c.Assert(conv, Equals, second)
+	c.Assert(guided.end("U1", first), Equals, false)
+	c.Assert(guided.end("U1", second), Equals, true)
+	_, ok = guided.get("U1", "D1")
+	c.Assert(ok, Equals, false)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gravitational/trace"
+)
+
+const (
+	userDirectoryFile = "./userdirectory"
+)
+
+var (
+	// slackMentionRegex finds a slack user mention, eg <@U1234ABCD> or <@U1234ABCD|aj>
+	slackMentionRegex = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|[^>]*)?>`)
+	// gitHubMentionRegex finds a github @login that isn't part of an email or path
+	gitHubMentionRegex = regexp.MustCompile(`(^|[^\w@/])@([A-Za-z0-9](?:[A-Za-z0-9-]{0,38}))\b`)
+)
+
+// directoryEntry is one slack user's github identity.
+type directoryEntry struct {
+	// Login is the github login
+	Login string `json:"login"`
+	// Manual is true if an admin added the entry, false if it came from a registered token
+	Manual bool `json:"manual"`
+}
+
+// userDirectory maps slack user IDs to github logins and back. It's persisted to a file.
+type userDirectory struct {
+	mu       sync.RWMutex
+	file     string
+	bySlack  map[string]directoryEntry
+	byGitHub map[string]string // lowercased login -> slack user ID
+}
+
+// newUserDirectory creates a directory and loads it from file if the file exists.
+func newUserDirectory(file string) (*userDirectory, error) {
+	d := &userDirectory{
+		file:     file,
+		bySlack:  make(map[string]directoryEntry),
+		byGitHub: make(map[string]string),
+	}
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return d, nil
+		}
+		return d, trace.Wrap(err)
+	}
+	if err := json.Unmarshal(contents, &d.bySlack); err != nil {
+		return d, trace.Wrap(err)
+	}
+	for user, entry := range d.bySlack {
+		d.byGitHub[strings.ToLower(entry.Login)] = user
+	}
+	return d, nil
+}
+
+// save writes the directory to its file. Caller must hold the lock.
+func (d *userDirectory) save() error {
+	contents, err := json.Marshal(d.bySlack)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(ioutil.WriteFile(d.file, contents, 0600))
+}
+
+// Set associates a slack user with a github login. A manual entry isn't overwritten by a token entry.
+func (d *userDirectory) Set(user, login string, manual bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if old, ok := d.bySlack[user]; ok {
+		if old.Manual && !manual {
+			return nil
+		}
+		delete(d.byGitHub, strings.ToLower(old.Login))
+	}
+	d.bySlack[user] = directoryEntry{Login: login, Manual: manual}
+	d.byGitHub[strings.ToLower(login)] = user
+	return d.save()
+}
+
+// Remove deletes a slack user's entry. If manual is false, only token entries are removed.
+func (d *userDirectory) Remove(user string, manual bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	old, ok := d.bySlack[user]
+	if !ok || (old.Manual && !manual) {
+		return nil
+	}
+	delete(d.bySlack, user)
+	delete(d.byGitHub, strings.ToLower(old.Login))
+	return d.save()
+}
+
+// Login returns the github login of a slack user.
+func (d *userDirectory) Login(user string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	entry, ok := d.bySlack[user]
+	return entry.Login, ok
+}
+
+// SlackUser returns the slack user ID of a github login.
+func (d *userDirectory) SlackUser(login string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	user, ok := d.byGitHub[strings.ToLower(login)]
+	return user, ok
+}
+
+// ToGitHub replaces known slack mentions in text with github @logins. Unknown mentions are left alone.
+func (d *userDirectory) ToGitHub(text string) string {
+	return slackMentionRegex.ReplaceAllStringFunc(text, func(mention string) string {
+		login, ok := d.Login(slackMentionRegex.FindStringSubmatch(mention)[1])
+		if !ok {
+			return mention
+		}
+		return "@" + login
+	})
+}
+
+// ToSlack replaces known github @logins in text with slack mentions.
+func (d *userDirectory) ToSlack(text string) string {
+	return gitHubMentionRegex.ReplaceAllStringFunc(text, func(mention string) string {
+		match := gitHubMentionRegex.FindStringSubmatch(mention)
+		user, ok := d.SlackUser(match[2])
+		if !ok {
+			return mention
+		}
+		return match[1] + "<@" + user + ">"
+	})
+}
+
+// ResolveAssignee turns a slack mention or an @login into a github login.
+func (d *userDirectory) ResolveAssignee(name string) (string, bool) {
+	if match := slackMentionRegex.FindStringSubmatch(name); match != nil {
+		return d.Login(match[1])
+	}
+	login := strings.TrimPrefix(name, "@")
+	return login, len(login) != 0
+}
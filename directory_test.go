@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type DirectorySuite struct {
+	dir string
+}
+
+var _ = Suite(&DirectorySuite{})
+
+func (s *DirectorySuite) SetUpTest(c *C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "issuebot")
+	c.Assert(err, IsNil)
+}
+
+func (s *DirectorySuite) TearDownTest(c *C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *DirectorySuite) TestConversion(c *C) {
+	file := filepath.Join(s.dir, "userdirectory")
+	d, err := newUserDirectory(file)
+	c.Assert(err, IsNil)
+	c.Assert(d.Set("U1234ABCD", "ayjayt", false), IsNil)
+
+	c.Assert(d.ToGitHub("cc <@U1234ABCD> and <@U9999ZZZZ|someone>"), Equals, "cc @ayjayt and <@U9999ZZZZ|someone>")
+	c.Assert(d.ToSlack("@AyJayT, not aj@ayjayt.com or @stranger"), Equals, "<@U1234ABCD>, not aj@ayjayt.com or @stranger")
+
+	// A token entry doesn't overwrite a manual one, and it survives a reload
+	c.Assert(d.Set("U1234ABCD", "manual-login", true), IsNil)
+	c.Assert(d.Set("U1234ABCD", "token-login", false), IsNil)
+	d, err = newUserDirectory(file)
+	c.Assert(err, IsNil)
+	login, ok := d.Login("U1234ABCD")
+	c.Assert(ok, Equals, true)
+	c.Assert(login, Equals, "manual-login")
+
+	c.Assert(d.Remove("U1234ABCD", false), IsNil)
+	_, ok = d.Login("U1234ABCD")
+	c.Assert(ok, Equals, true)
+	c.Assert(d.Remove("U1234ABCD", true), IsNil)
+	_, ok = d.Login("U1234ABCD")
+	c.Assert(ok, Equals, false)
+}
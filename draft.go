@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/shomali11/proper"
+)
+
+const (
+	// draftStoreFile is where drafts are kept, by slack user
+	draftStoreFile = "./drafts"
+)
+
+var (
+	// draftRegex matches "draft <action> <args>", where args can span lines
+	draftRegex = regexp.MustCompile(`(?s)^\s*(?:<@(\S+)>)?\s*draft\s+(start|add|show|submit|cancel)\b\s*(.*?)\s*$`)
+	// draftStartRegex matches the arguments of "draft start", an optional repo and a quoted title
+	draftStartRegex = regexp.MustCompile(`^(?:("[^"]*"|[^\s"]+)\s+)?"([^"\\]*(?:\\.[^"\\]*)*)"$`)
+	// ErrNoDraft is reported when someone uses a draft they haven't started
+	ErrNoDraft = errors.New("You don't have a draft, start one with draft start \"repo\" \"title\"")
+)
+
+// draft is an issue that's built up over several messages before it's filed.
+type draft struct {
+	Repo    string
+	Title   string
+	Parts   []string
+	Started time.Time
+}
+
+// body is the draft's issue body.
+func (d *draft) body() string {
+	return strings.Join(d.Parts, "\n\n")
+}
+
+// draftParser matches the draft commands.
+func (s *SlackBot) draftParser(text string) (*proper.Properties, bool) {
+	resultSlice := draftRegex.FindStringSubmatch(text)
+	if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
+		return nil, false
+	}
+	return proper.NewProperties(map[string]string{"action": resultSlice[2], "args": resultSlice[3]}), true
+}
+
+// draftCommand is the callback for the draft commands.
+func (s *SlackBot) draftCommand(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	user := r.Event().User
+	action, args := r.StringParam("action", ""), r.StringParam("args", "")
+	if action == "start" {
+		s.startDraft(r, w, args)
+		return
+	}
+
+	var d draft
+	ok, err := s.drafts.Get(user, &d)
+	if err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	if !ok {
+		w.ReportError(ErrNoDraft)
+		return
+	}
+	switch action {
+	case "add":
+		s.addToDraft(r, w, &d, args)
+	case "show":
+		body := "_Nothing yet, add to it with `draft add`_"
+		if len(d.Parts) != 0 {
+			body = "```" + excerpt(d.body()) + "```"
+		}
+		w.Reply(fmt.Sprintf("*%v* in %v\n%v", d.Title, d.Repo, body))
+	case "submit":
+		s.submitDraft(r, w, &d)
+	case "cancel":
+		if err := s.drafts.Delete(user); err != nil {
+			log.Errorf(trace.DebugReport(err))
+		}
+		w.Reply(fmt.Sprintf("Threw away the draft of _%v_", d.Title))
+	}
+}
+
+// startDraft starts a draft, in the channel's repo if one isn't given.
+func (s *SlackBot) startDraft(r request, w responder, args string) {
+	event := r.Event()
+	match := draftStartRegex.FindStringSubmatch(args)
+	if match == nil {
+		w.ReportError(errors.New(`Start a draft with draft start "repo" "title"`))
+		return
+	}
+	repo := strings.Trim(match[1], `"`)
+	if len(repo) == 0 {
+		repo = s.settings.Channels[event.Channel].Repo
+	}
+	if len(repo) == 0 {
+		w.ReportError(errors.New("This channel doesn't have a repo, say which one"))
+		return
+	}
+	var existing draft
+	if ok, _ := s.drafts.Get(event.User, &existing); ok {
+		w.ReportError(fmt.Errorf("You're already drafting _%v_, submit or cancel it first", existing.Title))
+		return
+	}
+	d := draft{
+		Repo:    repo,
+		Title:   s.directory.ToGitHub(escapeRegex.ReplaceAllString(match[2], "$1")),
+		Started: time.Now().UTC(),
+	}
+	if err := s.drafts.Put(event.User, d); err != nil {
+		w.ReportError(errors.New("Couldn't save your draft"))
+		log.Errorf(trace.DebugReport(err))
+		return
+	}
+	w.Reply(fmt.Sprintf("Started a draft of _%v_ in %v. Add to it with `draft add`, then `draft submit` it", d.Title, d.Repo))
+}
+
+// addToDraft appends a message, and the files shared with it, to a draft.
+func (s *SlackBot) addToDraft(r request, w responder, d *draft, text string) {
+	event := r.Event()
+	var part []string
+	if len(text) != 0 {
+		part = append(part, s.toGitHub(text))
+	}
+	if len(event.Files) != 0 {
+		budget := MaxFileBytes - len(strings.Join(d.Parts, "\n\n"))
+		part = append(part, s.filesToMarkdown(event.Files, &budget))
+	}
+	if len(part) == 0 {
+		w.ReportError(errors.New("Add some text, a snippet or a file"))
+		return
+	}
+	d.Parts = append(d.Parts, strings.Join(part, "\n\n"))
+	if err := s.drafts.Put(event.User, d); err != nil {
+		w.ReportError(errors.New("Couldn't save your draft"))
+		log.Errorf(trace.DebugReport(err))
+		return
+	}
+	w.Reply(fmt.Sprintf("Added to _%v_, it has %v parts", d.Title, len(d.Parts)))
+}
+
+// submitDraft files a draft, through the repo's preview and duplicate checks.
+func (s *SlackBot) submitDraft(r request, w responder, d *draft) {
+	event := r.Event()
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	body := withAttribution(d.body(), s.commandAttribution(subCtx, r, "Filed"))
+	// The draft is done with either way, a preview or possible duplicates hold their own copy
+	pending := pendingIssue{Repo: d.Repo, Title: d.Title, Body: body}
+	if s.previewIssue(r, w, pending) || s.checkDuplicates(subCtx, r, w, client, pending) {
+		if err := s.drafts.Delete(event.User); err != nil {
+			log.Errorf(trace.DebugReport(err))
+		}
+		return
+	}
+	issue, err := client.NewIssue(subCtx, d.Repo, d.Title, body, IssueFields{})
+	if err != nil {
+		w.ReportError(errors.New("There was an error with the GitHub interface... Check 1) the repo name 2) the logs"))
+		log.Infof("draft issue error: %v", trace.DebugReport(err))
+		return
+	}
+	if err := s.drafts.Delete(event.User); err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	s.recordIssue(event.User, event.Channel, event.ThreadTimestamp, issue)
+	s.postNewIssue(r, w, issue)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type DraftSuite struct{}
+
+var _ = Suite(&DraftSuite{})
+
+func (s *DraftSuite) TestDraftParser(c *C) {
+	bot := &SlackBot{botID: "UBOT"}
+	properties, ok := bot.draftParser("<@UBOT> draft add first line\nsecond line")
+	c.Assert(ok, Equals, true)
+	c.Assert(properties.StringParam("action", ""), Equals, "add")
+	c.Assert(properties.StringParam("args", ""), Equals, "first line\nsecond line")
+
+	properties, ok = bot.draftParser("draft submit")
+	c.Assert(ok, Equals, true)
+	c.Assert(properties.StringParam("action", ""), Equals, "submit")
+
+	_, ok = bot.draftParser("draft publish")
+	c.Assert(ok, Equals, false)
+	_, ok = bot.draftParser("draft address")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *DraftSuite) TestDraftStart(c *C) {
+	for _, args := range []string{`"gravitational/teleport" "a title"`, `gravitational/teleport "a title"`} {
+		match := draftStartRegex.FindStringSubmatch(args)
+		c.Assert(match, NotNil, Commentf(args))
+		c.Assert(match[1], Matches, `"?gravitational/teleport"?`)
+		c.Assert(match[2], Equals, "a title")
+	}
+	match := draftStartRegex.FindStringSubmatch(`"a title"`)
+	c.Assert(match, NotNil)
+	c.Assert(match[1], Equals, "")
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+const (
+	// DuplicateThreshold is how much two titles' words must overlap for an issue to look like a duplicate
+	DuplicateThreshold = 0.5
+	// MaxDuplicates is how many possible duplicates are shown
+	MaxDuplicates = 3
+	// PendingIssueMinutes is how long an issue waits for "File anyway" before it's forgotten
+	PendingIssueMinutes = 60
+	// duplicateSearchTerms is how many title words are searched for, github allows 5 ORs
+	duplicateSearchTerms = 6
+
+	// fileAnywayActionID identifies the "File anyway" button
+	fileAnywayActionID = "duplicate_file"
+	// plusOneActionID identifies the "+1 instead" buttons
+	plusOneActionID = "duplicate_plus_one"
+	// cancelPendingActionID identifies the "Cancel" button
+	cancelPendingActionID = "duplicate_cancel"
+)
+
+// stopWords are left out when comparing titles.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"can": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "the": true, "to": true, "when": true, "with": true, "not": true, "does": true,
+}
+
+// titleWords splits a title into its distinct lowercase words, leaving out stop words.
+func titleWords(title string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool)
+	var words []string
+	for _, word := range fields {
+		if stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
+
+// titleSimilarity is the share of words two titles have in common, from 0 to 1.
+func titleSimilarity(a, b string) float64 {
+	aWords, bWords := titleWords(a), titleWords(b)
+	if len(aWords) == 0 || len(bWords) == 0 {
+		return 0
+	}
+	inA := make(map[string]bool)
+	for _, word := range aWords {
+		inA[word] = true
+	}
+	common := 0
+	for _, word := range bWords {
+		if inA[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(aWords)+len(bWords)-common)
+}
+
+// findDuplicates searches repo for open issues whose titles look like title, best match first.
+func findDuplicates(ctx context.Context, client *GitHubIssueBot, repo, title string) ([]IssueDetails, error) {
+	words := titleWords(title)
+	if len(words) == 0 {
+		return nil, nil
+	}
+	if len(words) > duplicateSearchTerms {
+		words = words[:duplicateSearchTerms]
+	}
+	query := fmt.Sprintf("repo:%v is:issue is:open in:title %v", repo, strings.Join(words, " OR "))
+	results, err := client.SearchIssues(ctx, query, 20, "")
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	type scored struct {
+		issue IssueDetails
+		score float64
+	}
+	var matches []scored
+	for _, issue := range results.Issues {
+		if score := titleSimilarity(title, issue.Title); score >= DuplicateThreshold {
+			matches = append(matches, scored{issue: issue, score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	var duplicates []IssueDetails
+	for i := 0; i < len(matches) && i < MaxDuplicates; i++ {
+		duplicates = append(duplicates, matches[i].issue)
+	}
+	return duplicates, nil
+}
+
+// pendingIssue is an issue that's waiting for its author to decide what to do about possible duplicates.
+type pendingIssue struct {
+	User    string
+	Channel string
+	Thread  string
+	Repo    string
+	Title   string
+	Body    string
+	Fields  IssueFields
+	// Template is the template whose form is being filled in, if any
+	Template *issueTemplate
+	created  time.Time
+}
+
+// pendingIssues holds issues until their authors click "File anyway", "+1 instead" or "Cancel".
+type pendingIssues struct {
+	mu     sync.Mutex
+	next   int
+	issues map[string]pendingIssue
+}
+
+// add stores an issue and returns its ID.
+func (p *pendingIssues) add(issue pendingIssue) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.issues == nil {
+		p.issues = make(map[string]pendingIssue)
+	}
+	now := time.Now()
+	for id, pending := range p.issues {
+		if now.Sub(pending.created) > time.Minute*PendingIssueMinutes {
+			delete(p.issues, id)
+		}
+	}
+	p.next++
+	id := strconv.Itoa(p.next)
+	issue.created = now
+	p.issues[id] = issue
+	return id
+}
+
+// take removes and returns an issue, but only for the user who started it.
+func (p *pendingIssues) take(id, user string) (pendingIssue, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	issue, ok := p.lookup(id, user)
+	if ok {
+		delete(p.issues, id)
+	}
+	return issue, ok
+}
+
+// get returns an issue without removing it, but only for the user who started it.
+func (p *pendingIssues) get(id, user string) (pendingIssue, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lookup(id, user)
+}
+
+// lookup finds a user's issue. The caller holds mu.
+func (p *pendingIssues) lookup(id, user string) (pendingIssue, bool) {
+	issue, ok := p.issues[id]
+	if !ok || issue.User != user || time.Since(issue.created) > time.Minute*PendingIssueMinutes {
+		return pendingIssue{}, false
+	}
+	return issue, true
+}
+
+// checkDuplicates looks for possible duplicates of an issue before it's filed. If there are any, it
+// shows them with buttons to file anyway or +1 one of them, and returns true; the issue is then filed
+// when the button is clicked. If the search fails the issue isn't held up, but the failure is logged as
+// an error since every issue is then filed unchecked.
+func (s *SlackBot) checkDuplicates(ctx context.Context, r request, w responder, client *GitHubIssueBot, issue pendingIssue) bool {
+	duplicates, err := findDuplicates(ctx, client, issue.Repo, issue.Title)
+	if err != nil {
+		log.Errorf("couldn't search %v for duplicates of %q, filing it unchecked: %v", issue.Repo, issue.Title, trace.DebugReport(err))
+		return false
+	}
+	if len(duplicates) == 0 {
+		return false
+	}
+	event := r.Event()
+	issue.User, issue.Channel, issue.Thread = event.User, event.Channel, event.ThreadTimestamp
+	id := s.pending.add(issue)
+
+	mrkdwn := func(text string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.MarkdownType, text, false, false)
+	}
+	button := func(actionID, value, text string) *slack.ButtonBlockElement {
+		return slack.NewButtonBlockElement(actionID, value, slack.NewTextBlockObject(slack.PlainTextType, text, false, false))
+	}
+	lines := []string{fmt.Sprintf("*%v* might already be filed in %v:", issue.Title, issue.Repo)}
+	blocks := []slack.Block{slack.NewSectionBlock(mrkdwn(lines[0]), nil, nil)}
+	for _, duplicate := range duplicates {
+		line := fmt.Sprintf("<%v|%v> %v", duplicate.Url, duplicate.Ref, escapeMrkdwn(duplicate.Title))
+		lines = append(lines, line)
+		blocks = append(blocks, slack.NewSectionBlock(mrkdwn(line), nil,
+			slack.NewAccessory(button(plusOneActionID, id+" "+duplicate.Ref.String(), "+1 instead"))))
+	}
+	fileAnyway := button(fileAnywayActionID, id, "File anyway")
+	fileAnyway.Style = slack.StylePrimary
+	blocks = append(blocks, slack.NewActionBlock("", fileAnyway, button(cancelPendingActionID, id, "Cancel")))
+
+	options := []slack.MsgOption{
+		slack.MsgOptionText(strings.Join(lines, "\n"), false),
+		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+	if len(event.ThreadTimestamp) != 0 {
+		options = append(options, slack.MsgOptionTS(event.ThreadTimestamp))
+	}
+	// Only the reporter can click the buttons, so only they see them
+	if _, err = s.sBot.Client().PostEphemeral(event.Channel, event.User, options...); err != nil {
+		// Eg a slash command in a channel the bot isn't in, where the buttons can't be shown
+		log.Infof("couldn't post duplicates: %v", err)
+		s.pending.take(id, event.User)
+		w.Reply(strings.Join(append(lines, "Use `new` from a channel the bot is in to file it anyway."), "\n"))
+	}
+	return true
+}
+
+// resolvePending handles the buttons on a possible duplicates message or a preview.
+func (s *SlackBot) resolvePending(ctx context.Context, payload *interaction, actionID, value string) {
+	if !s.Begin() {
+		return
+	}
+	defer s.Done()
+	id := strings.Fields(value + " ")[0]
+	issue, ok := s.pending.take(id, payload.User.ID)
+	if !ok {
+		log.Infof("%v clicked %v on a pending issue that isn't theirs or has expired", payload.User.ID, actionID)
+		return
+	}
+	reply := func(text string) {
+		if err := postResponseURL(payload.ResponseURL, slashResponse{Text: text, ReplaceOriginal: true}); err != nil {
+			log.Errorf("couldn't post to response_url: %v", trace.DebugReport(err))
+		}
+	}
+	if actionID == cancelPendingActionID {
+		reply(fmt.Sprintf("Didn't file _%v_", issue.Title))
+		return
+	}
+	if actionID == editPendingActionID {
+		draft := issueDraft{Repo: issue.Repo, Title: issue.Title, Body: issue.Body, Fields: issue.Fields, Thread: issue.Thread}
+		if err := s.openIssueModal(ctx, payload.TriggerID, issue.User, issue.Channel, draft, nil); err != nil {
+			log.Infof("couldn't open modal to edit preview: %v", trace.DebugReport(err))
+			reply(fmt.Sprintf("*Error:* _Couldn't open the form: %v_", trace.UserMessage(err)))
+			return
+		}
+		reply(fmt.Sprintf("Editing _%v_ in the form", issue.Title))
+		return
+	}
+
+	client := s.getGBotForUser(ctx, issue.User)
+	if client == nil {
+		reply("*Error:* _You're not registered anymore_")
+		return
+	}
+	subCtx, cancel := context.WithTimeout(ctx, time.Second*TimeoutSeconds)
+	defer cancel()
+	switch actionID {
+	case fileAnywayActionID:
+		created, err := client.NewIssue(subCtx, issue.Repo, issue.Title, issue.Body, issue.Fields)
+		if err != nil {
+			log.Infof("new issue error: %v", trace.DebugReport(err))
+			reply("*Error:* _There was an error with the GitHub interface... Check 1) the repo name 2) the logs_")
+			return
+		}
+		s.recordIssue(issue.User, issue.Channel, issue.Thread, created)
+		reply(created.Url)
+	case plusOneActionID:
+		ref, ok := parseIssueRef(strings.TrimPrefix(value, id+" "), "")
+		if !ok {
+			log.Infof("bad +1 button value %q", value)
+			return
+		}
+		body := fmt.Sprintf("+1, this was also reported from Slack as _%v_", issue.Title)
+		if len(strings.TrimSpace(issue.Body)) != 0 {
+			body += "\n\n" + issue.Body
+		}
+		url, err := client.AddComment(subCtx, ref, body)
+		if err != nil {
+			log.Infof("+1 comment error: %v", trace.DebugReport(err))
+			reply(fmt.Sprintf("*Error:* _Couldn't comment on %v_", ref))
+			return
+		}
+		reply(fmt.Sprintf("Added a +1 to %v: %v", ref, url))
+	}
+}
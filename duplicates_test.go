@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+
+	"github.com/nlopes/slack"
+	. "gopkg.in/check.v1"
+)
+
+type DuplicatesSuite struct{}
+
+var _ = Suite(&DuplicatesSuite{})
+
+func (s *DuplicatesSuite) TestTitleWords(c *C) {
+	c.Assert(titleWords("The SSO login fails, fails with a 500!"), DeepEquals, []string{"sso", "login", "fails", "500"})
+	c.Assert(titleWords("the a of"), IsNil)
+}
+
+func (s *DuplicatesSuite) TestTitleSimilarity(c *C) {
+	testTables := []struct {
+		name    string
+		a       string
+		b       string
+		similar bool
+	}{
+		{name: "Reordered", a: "Login fails with SSO", b: "SSO login fails", similar: true},
+		{name: "Mostly Same", a: "tsh login hangs on macOS", b: "tsh login hangs", similar: true},
+		{name: "One Word In Common", a: "tsh login hangs", b: "web UI login button is misaligned", similar: false},
+		{name: "Nothing In Common", a: "Add dark mode", b: "Crash on startup", similar: false},
+		{name: "Only Stop Words", a: "the", b: "the", similar: false},
+	}
+	for i, tt := range testTables {
+		score := titleSimilarity(tt.a, tt.b)
+		c.Assert(score >= DuplicateThreshold, Equals, tt.similar, Commentf("test #%d (%v): scored %v", i+1, tt.name, score))
+	}
+}
+
+// testRequest is a request for a message, with the parameters its command's parser found.
+type testRequest struct {
+	event  slack.MessageEvent
+	params map[string]string
+}
+
+func (r *testRequest) Context() context.Context { return context.Background() }
+
+func (r *testRequest) Event() *slack.MessageEvent { return &r.event }
+
+func (r *testRequest) StringParam(key, defaultValue string) string {
+	if value, ok := r.params[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// testResponder keeps what's said in reply.
+type testResponder struct {
+	replies []string
+	errors  []error
+}
+
+func (w *testResponder) Reply(text string) { w.replies = append(w.replies, text) }
+
+func (w *testResponder) ReportError(err error) { w.errors = append(w.errors, err) }
+
+func (s *DuplicatesSuite) TestFindDuplicates(c *C) {
+	var queries []string
+	response := `{"data": {"search": {"issueCount": 2, "nodes": [
+		{"__typename": "Issue", "number": 1, "title": "Web UI login button is misaligned", "state": "OPEN"},
+		{"__typename": "Issue", "number": 2, "title": "tsh login hangs on macOS", "state": "OPEN"}]}}}`
+	client, done := testGitHub(c, response, &queries)
+	defer done()
+	duplicates, err := findDuplicates(context.Background(), client, "gravitational/teleport", "tsh login hangs")
+	c.Assert(err, IsNil)
+	c.Assert(duplicates, HasLen, 1)
+	c.Assert(duplicates[0].Ref.Number, Equals, 2)
+	c.Assert(queries, HasLen, 1)
+}
+
+func (s *DuplicatesSuite) TestSearchErrorDoesNotHoldUpIssue(c *C) {
+	var queries []string
+	client, done := testGitHub(c, `{"errors": [{"message": "Something went wrong"}]}`, &queries)
+	defer done()
+	_, err := findDuplicates(context.Background(), client, "gravitational/teleport", "tsh login hangs")
+	c.Assert(err, ErrorMatches, ".*Something went wrong.*")
+
+	bot := &SlackBot{}
+	r := &testRequest{event: slack.MessageEvent{Msg: slack.Msg{User: "U1", Channel: "C1"}}}
+	w := &testResponder{}
+	held := bot.checkDuplicates(context.Background(), r, w, client, pendingIssue{Repo: "gravitational/teleport", Title: "tsh login hangs"})
+	c.Assert(held, Equals, false)
+	c.Assert(w.replies, HasLen, 0)
+	c.Assert(w.errors, HasLen, 0)
+	c.Assert(bot.pending.issues, HasLen, 0)
+}
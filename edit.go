@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+)
+
+var (
+	// editRegex matches the arguments of "edit", a field and a quoted value, see issueRegex
+	editRegex = regexp.MustCompile(`^(title|body)\s+"([^"\\]*(?:\\.[^"\\]*)*)"$`)
+	// ErrNotCreator is reported when someone edits an issue they didn't file through the bot
+	ErrNotCreator = errors.New("You can only edit issues you filed through me")
+)
+
+// editIssue is the callback for the "edit" command, which changes the title or body of an issue
+// the user filed through the bot.
+func (s *SlackBot) editIssue(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	match := editRegex.FindStringSubmatch(r.StringParam("args", ""))
+	if match == nil {
+		w.ReportError(errors.New(`Say what to change, eg edit teleport#4521 title "the new title"`))
+		return
+	}
+	field, value := match[1], escapeRegex.ReplaceAllString(match[2], "$1")
+	ref, ok := s.commandIssue(r)
+	if !ok {
+		w.ReportError(ErrNoIssue)
+		return
+	}
+	if !s.createdBy(ref, event.User) {
+		w.ReportError(ErrNotCreator)
+		return
+	}
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	var title, body *string
+	if field == "title" {
+		value = s.directory.ToGitHub(value)
+		title = &value
+	} else {
+		value = s.toGitHub(value) + "\n\n" + s.commandAttribution(subCtx, r, "Edited")
+		body = &value
+	}
+	if err := client.UpdateIssue(subCtx, ref, title, body); err != nil {
+		w.ReportError(fmt.Errorf("Couldn't edit %v", ref))
+		log.Infof("edit error: %v", trace.DebugReport(err))
+		return
+	}
+	s.audit(event.User, "edit "+field, ref, excerpt(value))
+	s.replyInThread(r, w, fmt.Sprintf("Changed the %v of %v", field, ref))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+const (
+	// ExpandMemoryHours is how long the bot remembers a reference it expanded in a thread
+	ExpandMemoryHours = 24
+)
+
+// expandedRefs remembers which references were expanded in which threads.
+type expandedRefs struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// markNew records that ref was expanded in thread, and reports whether it hadn't been already.
+func (e *expandedRefs) markNew(thread string, ref issueRef) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.seen == nil {
+		e.seen = make(map[string]time.Time)
+	}
+	now := time.Now()
+	for key, when := range e.seen {
+		if now.Sub(when) > time.Hour*ExpandMemoryHours {
+			delete(e.seen, key)
+		}
+	}
+	key := thread + " " + ref.String()
+	if _, ok := e.seen[key]; ok {
+		return false
+	}
+	e.seen[key] = now
+	return true
+}
+
+// expandReferences replies in-thread with a summary of each owner/repo#N or repo#N in a message,
+// in channels that opted in. It's the default command, so it sees messages that aren't commands.
+func (s *SlackBot) expandReferences(r request) {
+	event := r.Event()
+	channel := s.settings.Channels[event.Channel]
+	if !channel.Expand {
+		return
+	}
+	owner := s.DefaultOwner(event.Channel)
+	thread := event.ThreadTimestamp
+	if len(thread) == 0 {
+		thread = event.Timestamp
+	}
+	var refs []issueRef
+	for _, ref := range findIssueRefs(event.Text, owner) {
+		if s.expanded.markNew(event.Channel+"/"+thread, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == 0 {
+		return
+	}
+	if !s.Begin() {
+		return
+	}
+	defer s.Done()
+	client, fellBack := s.gBotFor(r.Context(), event.User)
+	if client == nil {
+		return
+	}
+
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	var lines []string
+	for _, ref := range refs {
+		details, err := getIssueFor(subCtx, client, fellBack, ref)
+		if err != nil {
+			log.Infof("couldn't expand %v: %v", ref, err)
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("<%v|%v> %v · _%v_",
+			details.Url, ref, escapeMrkdwn(details.Title), strings.ToLower(details.State)))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	_, _, err := s.sBot.Client().PostMessage(event.Channel,
+		slack.MsgOptionText(strings.Join(lines, "\n"), false),
+		slack.MsgOptionTS(thread),
+		slack.MsgOptionDisableLinkUnfurl())
+	if err != nil {
+		log.Errorf("couldn't expand references: %v", trace.DebugReport(err))
+	}
+}
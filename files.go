@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+const (
+	// MaxFileBytes is how much of an issue body can be copied in from snippets and text files. github caps
+	// a body at 65,536 characters, this leaves room for the rest of it. Files past it are linked.
+	MaxFileBytes = 60000
+)
+
+// snippetLanguages maps the slack snippet types that github calls something else. Types missing
+// from here are the same on both.
+var snippetLanguages = map[string]string{
+	"auto":  "",
+	"text":  "",
+	"post":  "",
+	"space": "",
+	"objc":  "objective-c",
+	"vb":    "vbnet",
+	"vbs":   "vbscript",
+	"latex": "tex",
+	"shell": "sh",
+	"bash":  "sh",
+}
+
+// snippetLanguage is the language of a code block for a slack snippet type.
+func snippetLanguage(filetype string) string {
+	if language, ok := snippetLanguages[filetype]; ok {
+		return language
+	}
+	return filetype
+}
+
+// isText reports whether a file can be copied into an issue as text.
+func isText(file slack.File) bool {
+	return file.Mode == "snippet" || strings.HasPrefix(file.Mimetype, "text/")
+}
+
+// codeFence is a code block fence that's longer than any run of backticks in content, so the content
+// can't end the block early.
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		if run++; run > longest {
+			longest = run
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// snippetMarkdown renders a snippet or text file as a code block.
+func snippetMarkdown(file slack.File, content string) string {
+	content = strings.TrimRight(content, "\n")
+	fence := codeFence(content)
+	return fmt.Sprintf("**%v**\n%v%v\n%v\n%v", file.Title, fence, snippetLanguage(file.Filetype), content, fence)
+}
+
+// filesToMarkdown renders the files shared with a message for github. Snippets and text files are
+// copied in as code blocks while they fit in budget, the bytes left for files in the issue body, which
+// they're taken out of. Other files, and the ones that don't fit, are linked.
+func (s *SlackBot) filesToMarkdown(files []slack.File, budget *int) string {
+	var parts []string
+	for _, file := range files {
+		if isText(file) && file.Size <= *budget {
+			var content bytes.Buffer
+			err := s.sBot.Client().GetFile(file.URLPrivateDownload, &content)
+			if err != nil {
+				log.Infof("couldn't download snippet %v: %v", file.ID, err)
+			} else if snippet := snippetMarkdown(file, content.String()); len(snippet) <= *budget {
+				*budget -= len(snippet)
+				parts = append(parts, snippet)
+				continue
+			}
+		}
+		parts = append(parts, fmt.Sprintf(":paperclip: [%v](%v)", file.Name, file.Permalink))
+	}
+	return strings.Join(parts, "\n\n")
+}
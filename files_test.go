@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/nlopes/slack"
+	. "gopkg.in/check.v1"
+)
+
+type FilesSuite struct{}
+
+var _ = Suite(&FilesSuite{})
+
+func (s *FilesSuite) TestSnippetLanguage(c *C) {
+	c.Assert(snippetLanguage("go"), Equals, "go")
+	c.Assert(snippetLanguage("shell"), Equals, "sh")
+	c.Assert(snippetLanguage("text"), Equals, "")
+}
+
+func (s *FilesSuite) TestIsText(c *C) {
+	c.Assert(isText(slack.File{Mode: "snippet", Mimetype: "application/octet-stream"}), Equals, true)
+	c.Assert(isText(slack.File{Mode: "hosted", Mimetype: "text/plain"}), Equals, true)
+	c.Assert(isText(slack.File{Mode: "hosted", Mimetype: "image/png"}), Equals, false)
+}
+
+func (s *FilesSuite) TestCodeFence(c *C) {
+	testTables := []struct {
+		name    string
+		content string
+		fence   string
+	}{
+		{name: "No Backticks", content: "go build ./...", fence: "```"},
+		{name: "Inline Code", content: "run `make` then ``x``", fence: "```"},
+		{name: "Fenced Block", content: "```go\nfunc main() {}\n```", fence: "````"},
+		{name: "Longer Fence", content: "````\n```\n````", fence: "`````"},
+	}
+	for i, tt := range testTables {
+		c.Assert(codeFence(tt.content), Equals, tt.fence, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+	c.Assert(snippetMarkdown(slack.File{Title: "readme", Filetype: "markdown"}, "```sh\nls\n```\n"),
+		Equals, "**readme**\n````markdown\n```sh\nls\n```\n````")
+}
+
+func (s *FilesSuite) TestFilesOverBudgetAreLinked(c *C) {
+	bot := &SlackBot{}
+	files := []slack.File{
+		{Name: "big.log", Mode: "hosted", Mimetype: "text/plain", Size: MaxFileBytes + 1, Permalink: "https://slack.example/big"},
+		{Name: "screen.png", Mode: "hosted", Mimetype: "image/png", Size: 10, Permalink: "https://slack.example/png"},
+		{Name: "small.txt", Mode: "snippet", Size: 10, Permalink: "https://slack.example/small"},
+	}
+	budget := 5
+	c.Assert(bot.filesToMarkdown(files, &budget), Equals,
+		":paperclip: [big.log](https://slack.example/big)\n\n:paperclip: [screen.png](https://slack.example/png)\n\n:paperclip: [small.txt](https://slack.example/small)")
+	c.Assert(budget, Equals, 5)
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"flag"
 	"strings"
+	"time"
 
 	"github.com/gravitational/trace"
 	"github.com/mailgun/log"
@@ -28,6 +29,11 @@ var (
 		defaultAuthFilePath,
 		"What file contains a list of authorized users")
 
+	// flagOrg is the default github organization or user.
+	flagOrg = flag.String("org",
+		"",
+		"Specify the github organization or user that owns repos named without an owner, where a channel has no owner setting")
+
 	// flagSlackToken is a slack token.
 	flagSlackToken = flag.String("slack_token",
 		"",
@@ -37,13 +43,57 @@ var (
 	flagGitHubToken = flag.String("github_token",
 		"",
 		"Specify the github oauth token")
+
+	// flagAdmins is a comma separated list of slack users who can edit the user directory.
+	flagAdmins = flag.String("admins",
+		"",
+		"Comma separated list of slack user IDs allowed to run admin commands")
+
+	// flagSettingsFile is path to the JSON per-channel settings.
+	flagSettingsFile = flag.String("settings",
+		defaultSettingsFilePath,
+		"What file contains per-channel settings, eg the repo a channel files issues in")
+
+	// flagReaction is the emoji that turns a message into an issue.
+	flagReaction = flag.String("reaction",
+		"ticket",
+		"Reacting to a message with this emoji files it as an issue in the channel's repo")
+
+	// flagListen is the address of the HTTP server for slash commands.
+	flagListen = flag.String("listen",
+		"",
+		"Address to serve slack slash commands on, eg :8080 (disabled if empty)")
+
+	// flagSigningSecret is the slack app's signing secret.
+	flagSigningSecret = flag.String("signing_secret",
+		"",
+		"Specify the slack signing secret, required with --listen")
+
+	// flagUndoWindow is how long people can undo an issue they just filed.
+	flagUndoWindow = flag.Duration("undo_window",
+		5*time.Minute,
+		"How long after filing an issue it can be undone, 0 to disable undo")
+
+	// flagRulesFile is path to the JSON rules for routing and labeling new issues.
+	flagRulesFile = flag.String("rules",
+		defaultRulesFilePath,
+		"What file contains the rules that route, label and assign new issues")
 )
 
 type config struct {
-	slackToken  string
-	gitHubToken string
-	authFile    string
-	authedUsers []string
+	org           string
+	slackToken    string
+	gitHubToken   string
+	authFile      string
+	authedUsers   []string
+	admins        []string
+	settingsFile  string
+	settings      settings
+	reaction      string
+	listen        string
+	signingSecret string
+	undoWindow    time.Duration
+	rules         rules
 }
 
 func init() {
@@ -53,16 +103,33 @@ func init() {
 // flagHelper calls populateFlags with the flags above. These functions are
 // seperate to allow unit testing the logic in populateFlags.
 func flagHelper() (config, error) {
-	c, err := populateFlags(*flagSlackToken, *flagGitHubToken, *flagAuthFile)
+	c, err := populateFlags(*flagOrg, *flagSlackToken, *flagGitHubToken, *flagAuthFile)
 	if err != nil {
 		return c, err
 	}
 	err = c.loadAuthedUsers()
+	if err != nil {
+		return c, err
+	}
+	c.admins = splitList(*flagAdmins)
+	c.reaction = strings.Trim(*flagReaction, ":")
+	c.listen = *flagListen
+	c.signingSecret = *flagSigningSecret
+	if len(c.listen) != 0 && len(c.signingSecret) == 0 {
+		log.Errorf("You must specify a signing secret with --signing_secret to use --listen")
+		return c, trace.Wrap(ErrBadFlag)
+	}
+	c.undoWindow = *flagUndoWindow
+	c.settingsFile = *flagSettingsFile
+	if err = c.loadSettings(); err != nil {
+		return c, err
+	}
+	c.rules, err = loadRules(*flagRulesFile)
 	return c, err
 }
 
 // populateFlags checks flag validity and initializes a "config" struct.
-func populateFlags(slackToken, gitHubToken, authFile string) (config, error) {
+func populateFlags(org, slackToken, gitHubToken, authFile string) (config, error) {
 
 	c := config{}
 	// NOTE: It's more efficient (in the long run) to copy this structure by value
@@ -73,12 +140,22 @@ func populateFlags(slackToken, gitHubToken, authFile string) (config, error) {
 
 	var err error
 
+	if len(org) == 0 {
+		log.Errorf("You must specify a GitHub organization or user with --org")
+		err = ErrBadFlag
+	}
+	c.org = org
+
 	if len(slackToken) == 0 {
 		log.Errorf("You must specify a Slack token with --slack_token")
 		err = ErrBadFlag
 	}
 	c.slackToken = slackToken
 
+	if len(gitHubToken) == 0 {
+		log.Errorf("You must specify a GitHub token with --github_token")
+		err = ErrBadFlag
+	}
 	c.gitHubToken = gitHubToken
 
 	c.authFile = authFile
@@ -103,3 +180,14 @@ func (c *config) loadAuthedUsers() error {
 	c.authedUsers = authedUsers[:len(authedUsers)-1]
 	return nil
 }
+
+// splitList splits a comma separated flag value, dropping empty elements.
+func splitList(list string) []string {
+	var ret []string
+	for _, element := range strings.Split(list, ",") {
+		if element = strings.TrimSpace(element); len(element) != 0 {
+			ret = append(ret, element)
+		}
+	}
+	return ret
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gravitational/trace"
 	"github.com/mailgun/log"
@@ -13,6 +14,7 @@ import (
 )
 
 var (
+	// ErrBadRepo is returned when a repo isn't "owner/repo"
 	ErrBadRepo = errors.New("poorly formatted repo name")
 )
 
@@ -44,6 +46,11 @@ type Issue struct {
 	Url string
 }
 
+// Ref is the issue as owner/repo#N.
+func (i *Issue) Ref() issueRef {
+	return issueRef{Owner: i.Repository.Owner.Login, Repo: i.Repository.Name, Number: i.Number}
+}
+
 // NOTE graphQL basics-
 // graphQL Queries : SQL Select :: graphQL Mutations : SQL Upsert
 // 1) Create an input structure (for mutations) or
@@ -117,28 +124,44 @@ func (g *GitHubIssueBot) CheckToken(ctx context.Context) (name string, login str
 	return query.Viewer.Name, query.Viewer.Login, nil
 }
 
-// NewIssue takes a repo, issue, and issueBody and then creates a new issue.
-func (g *GitHubIssueBot) NewIssue(ctx context.Context, repo string, title string, body string) (*Issue, error) {
-	repoPath := strings.Split(repo, "/")
-	if len(repoPath) != 2 {
+// IssueFields are the optional parts of a new issue.
+type IssueFields struct {
+	// Labels are label names, which must already exist on the repo
+	Labels []string
+	// Assignees are github logins
+	Assignees []string
+	// Milestone is the title of an open milestone on the repo
+	Milestone string
+}
+
+// NewIssue takes a repo, issue, issueBody and optional fields and then creates a new issue.
+func (g *GitHubIssueBot) NewIssue(ctx context.Context, repo string, title string, body string, fields IssueFields) (*Issue, error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
 		// TODO: check channel for reponame
-		return nil, ErrBadRepo
+		return nil, err
 	}
 	// We need to see if the repo exists first. Search would still be better.
-	variables := map[string]interface{}{
-		"org":  githubv4.String(repoPath[0]),
-		"repo": githubv4.String(repoPath[1]),
+	repositoryID, err := g.repositoryID(ctx, owner, name)
+	if err != nil {
+		return nil, trace.Wrap(err)
 	}
-
-	var query struct {
-		Repository struct {
-			ID githubv4.ID
-		} `graphql:"repository(name: $repo, owner: $org)"`
+	labelIDs, err := g.labelIDs(ctx, owner, name, fields.Labels)
+	if err != nil {
+		return nil, trace.Wrap(err)
 	}
-
-	if err := g.client.Query(ctx, &query, variables); err != nil {
+	assigneeIDs, err := g.userIDs(ctx, fields.Assignees)
+	if err != nil {
 		return nil, trace.Wrap(err)
 	}
+	var milestoneID *githubv4.ID
+	if len(fields.Milestone) != 0 {
+		id, err := g.milestoneID(ctx, owner, name, fields.Milestone)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		milestoneID = &id
+	}
 
 	// NOTE: This type should eventually be provided by the GitHubV4 dependency
 	// NOTE: pkg githubv4 depends on this type name
@@ -146,13 +169,19 @@ func (g *GitHubIssueBot) NewIssue(ctx context.Context, repo string, title string
 		Title            githubv4.String  `json:"title"`
 		Body             githubv4.String  `json:"body"`
 		RepositoryId     githubv4.ID      `json:"repositoryId"`
+		LabelIds         []githubv4.ID    `json:"labelIds,omitempty"`
+		AssigneeIds      []githubv4.ID    `json:"assigneeIds,omitempty"`
+		MilestoneId      *githubv4.ID     `json:"milestoneId,omitempty"`
 		ClientMutationID *githubv4.String `json:"clientMutationId,omitempty"`
 	}
 
 	input := CreateIssueInput{
 		Title:        githubv4.String(title),
 		Body:         githubv4.String(body),
-		RepositoryId: query.Repository.ID,
+		RepositoryId: repositoryID,
+		LabelIds:     labelIDs,
+		AssigneeIds:  assigneeIDs,
+		MilestoneId:  milestoneID,
 	}
 
 	var m struct {
@@ -167,3 +196,919 @@ func (g *GitHubIssueBot) NewIssue(ctx context.Context, repo string, title string
 
 	return &m.CreateIssue.Issue, nil
 }
+
+// splitRepo splits "owner/repo" into its owner and name.
+func splitRepo(repo string) (owner string, name string, err error) {
+	repoPath := strings.Split(repo, "/")
+	if len(repoPath) != 2 || len(repoPath[0]) == 0 || len(repoPath[1]) == 0 {
+		return "", "", ErrBadRepo
+	}
+	return repoPath[0], repoPath[1], nil
+}
+
+// repositoryID finds the node ID of a repo.
+func (g *GitHubIssueBot) repositoryID(ctx context.Context, owner, name string) (githubv4.ID, error) {
+	variables := map[string]interface{}{
+		"org":  githubv4.String(owner),
+		"repo": githubv4.String(name),
+	}
+
+	var query struct {
+		Repository struct {
+			ID githubv4.ID
+		} `graphql:"repository(name: $repo, owner: $org)"`
+	}
+
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return query.Repository.ID, nil
+}
+
+// labelIDs finds the node IDs of labels on a repo by name.
+func (g *GitHubIssueBot) labelIDs(ctx context.Context, owner, name string, labels []string) ([]githubv4.ID, error) {
+	var ids []githubv4.ID
+	var unknown []string
+	for _, label := range labels {
+		id, err := g.labelID(ctx, owner, name, label)
+		if err != nil {
+			return nil, trace.Wrap(err)
+		}
+		if id == nil {
+			unknown = append(unknown, label)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if len(unknown) != 0 {
+		return nil, trace.BadParameter("unknown labels on %v/%v: %v", owner, name, strings.Join(unknown, ", "))
+	}
+	return ids, nil
+}
+
+// labelID finds the node ID of a label on a repo by name. It's nil if the repo doesn't have the label.
+func (g *GitHubIssueBot) labelID(ctx context.Context, owner, name, label string) (githubv4.ID, error) {
+	variables := map[string]interface{}{
+		"org":   githubv4.String(owner),
+		"repo":  githubv4.String(name),
+		"label": githubv4.String(label),
+	}
+	var query struct {
+		Repository struct {
+			Label *struct {
+				ID githubv4.ID
+			} `graphql:"label(name: $label)"`
+		} `graphql:"repository(name: $repo, owner: $org)"`
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if query.Repository.Label == nil {
+		return nil, nil
+	}
+	return query.Repository.Label.ID, nil
+}
+
+// KnownFields leaves out the labels a repo doesn't have and the users who can't be assigned its issues,
+// so eg a typo in a template doesn't stop the issue being filed. It returns what's left, and what was left out.
+func (g *GitHubIssueBot) KnownFields(ctx context.Context, repo string, fields IssueFields) (IssueFields, []string, error) {
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		return fields, nil, trace.Wrap(err)
+	}
+	known := fields
+	known.Labels, known.Assignees = nil, nil
+	var dropped []string
+	for _, label := range fields.Labels {
+		id, err := g.labelID(ctx, owner, name, label)
+		if err != nil {
+			return fields, nil, trace.Wrap(err)
+		}
+		if id == nil {
+			dropped = append(dropped, "label "+label)
+			continue
+		}
+		known.Labels = append(known.Labels, label)
+	}
+	for _, login := range fields.Assignees {
+		id, err := g.assigneeID(ctx, owner, name, login)
+		if err != nil {
+			return fields, nil, trace.Wrap(err)
+		}
+		if id == nil {
+			dropped = append(dropped, "assignee @"+login)
+			continue
+		}
+		known.Assignees = append(known.Assignees, login)
+	}
+	return known, dropped, nil
+}
+
+// milestoneID finds the node ID of an open milestone on a repo by title.
+func (g *GitHubIssueBot) milestoneID(ctx context.Context, owner, name, title string) (githubv4.ID, error) {
+	variables := map[string]interface{}{
+		"org":  githubv4.String(owner),
+		"repo": githubv4.String(name),
+	}
+	var query struct {
+		Repository struct {
+			Milestones struct {
+				Nodes []struct {
+					ID    githubv4.ID
+					Title string
+				}
+			} `graphql:"milestones(first: 100, states: OPEN)"`
+		} `graphql:"repository(name: $repo, owner: $org)"`
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, milestone := range query.Repository.Milestones.Nodes {
+		if strings.EqualFold(milestone.Title, title) {
+			return milestone.ID, nil
+		}
+	}
+	return nil, trace.BadParameter("no open milestone %q on %v/%v", title, owner, name)
+}
+
+// assigneeID finds the node ID of a user who can be assigned issues on a repo. It's nil if they can't be.
+func (g *GitHubIssueBot) assigneeID(ctx context.Context, owner, name, login string) (githubv4.ID, error) {
+	variables := map[string]interface{}{
+		"org":   githubv4.String(owner),
+		"repo":  githubv4.String(name),
+		"login": githubv4.String(login),
+	}
+	var query struct {
+		Repository struct {
+			AssignableUsers struct {
+				Nodes []struct {
+					ID    githubv4.ID
+					Login string
+				}
+			} `graphql:"assignableUsers(query: $login, first: 10)"`
+		} `graphql:"repository(name: $repo, owner: $org)"`
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	for _, user := range query.Repository.AssignableUsers.Nodes {
+		if strings.EqualFold(user.Login, login) {
+			return user.ID, nil
+		}
+	}
+	return nil, nil
+}
+
+// userIDs finds the node IDs of github users by login.
+func (g *GitHubIssueBot) userIDs(ctx context.Context, logins []string) ([]githubv4.ID, error) {
+	var ids []githubv4.ID
+	for _, login := range logins {
+		variables := map[string]interface{}{
+			"login": githubv4.String(login),
+		}
+		var query struct {
+			User struct {
+				ID githubv4.ID
+			} `graphql:"user(login: $login)"`
+		}
+		if err := g.client.Query(ctx, &query, variables); err != nil {
+			return nil, trace.Wrap(err)
+		}
+		ids = append(ids, query.User.ID)
+	}
+	return ids, nil
+}
+
+// Repositories lists the "owner/repo" names the token can see, most recently pushed first.
+func (g *GitHubIssueBot) Repositories(ctx context.Context, max int) ([]string, error) {
+	variables := map[string]interface{}{
+		"first": githubv4.Int(max),
+	}
+	var query struct {
+		Viewer struct {
+			Repositories struct {
+				Nodes []struct {
+					NameWithOwner string
+				}
+			} `graphql:"repositories(first: $first, affiliations: [OWNER, COLLABORATOR, ORGANIZATION_MEMBER], orderBy: {field: PUSHED_AT, direction: DESC})"`
+		}
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var repos []string
+	for _, node := range query.Viewer.Repositories.Nodes {
+		repos = append(repos, node.NameWithOwner)
+	}
+	return repos, nil
+}
+
+// IssueDetails describes an existing issue or pull request.
+type IssueDetails struct {
+	Ref           issueRef
+	Title         string
+	Url           string
+	State         string // OPEN, CLOSED or, for pull requests, MERGED
+	IsPullRequest bool
+	IsPrivate     bool // whether the repo is private, only looked up by GetIssue
+	Author        string
+	Body          string
+	Milestone     string
+	Labels        []string
+	Assignees     []string
+	Comments      int
+	// LatestComments are the last few comments, oldest first
+	LatestComments []IssueComment
+}
+
+// IssueComment is a comment on an issue or pull request.
+type IssueComment struct {
+	Author    string
+	Body      string
+	Url       string
+	CreatedAt time.Time
+}
+
+// issueDetailsFields are the fields issues and pull requests have in common. State isn't one of them,
+// it's an IssueState on issues and a PullRequestState on pull requests, and github rejects a query
+// that asks for both under the same name.
+// NOTE: This structure is declared by GitHub.com
+type issueDetailsFields struct {
+	Title  string
+	Url    string
+	Body   string
+	Author struct {
+		Login string
+	}
+	Milestone *struct {
+		Title string
+	}
+	Labels struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 20)"`
+	Assignees struct {
+		Nodes []struct {
+			Login string
+		}
+	} `graphql:"assignees(first: 10)"`
+	Comments struct {
+		TotalCount int
+		Nodes      []struct {
+			Author struct {
+				Login string
+			}
+			Body      string
+			Url       string
+			CreatedAt githubv4.DateTime
+		}
+	} `graphql:"comments(last: 3)"`
+}
+
+// GetIssue looks up an issue or pull request.
+func (g *GitHubIssueBot) GetIssue(ctx context.Context, ref issueRef) (*IssueDetails, error) {
+	variables := map[string]interface{}{
+		"org":    githubv4.String(ref.Owner),
+		"repo":   githubv4.String(ref.Repo),
+		"number": githubv4.Int(ref.Number),
+	}
+	var query struct {
+		Repository struct {
+			IsPrivate          bool
+			IssueOrPullRequest struct {
+				Typename string `graphql:"__typename"`
+				Issue    struct {
+					issueDetailsFields
+					State string
+				} `graphql:"... on Issue"`
+				PullRequest struct {
+					issueDetailsFields
+					State string `graphql:"prState: state"`
+				} `graphql:"... on PullRequest"`
+			} `graphql:"issueOrPullRequest(number: $number)"`
+		} `graphql:"repository(name: $repo, owner: $org)"`
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	result := query.Repository.IssueOrPullRequest
+	fields, state := result.Issue.issueDetailsFields, result.Issue.State
+	if result.Typename == "PullRequest" {
+		fields, state = result.PullRequest.issueDetailsFields, result.PullRequest.State
+	}
+	details := &IssueDetails{
+		Ref:           ref,
+		Title:         fields.Title,
+		Url:           fields.Url,
+		State:         state,
+		IsPullRequest: result.Typename == "PullRequest",
+		IsPrivate:     query.Repository.IsPrivate,
+		Author:        fields.Author.Login,
+		Body:          fields.Body,
+		Comments:      fields.Comments.TotalCount,
+	}
+	if fields.Milestone != nil {
+		details.Milestone = fields.Milestone.Title
+	}
+	for _, comment := range fields.Comments.Nodes {
+		details.LatestComments = append(details.LatestComments, IssueComment{
+			Author:    comment.Author.Login,
+			Body:      comment.Body,
+			Url:       comment.Url,
+			CreatedAt: comment.CreatedAt.Time,
+		})
+	}
+	for _, label := range fields.Labels.Nodes {
+		details.Labels = append(details.Labels, label.Name)
+	}
+	for _, assignee := range fields.Assignees.Nodes {
+		details.Assignees = append(details.Assignees, assignee.Login)
+	}
+	return details, nil
+}
+
+// SearchResults is a page of issue search results.
+type SearchResults struct {
+	Total     int
+	Issues    []IssueDetails
+	EndCursor string
+	HasMore   bool
+}
+
+// searchResultFields are the fields shown for each search result. Like issueDetailsFields, it
+// leaves out State.
+// NOTE: This structure is declared by GitHub.com
+type searchResultFields struct {
+	Number     int
+	Title      string
+	Url        string
+	Repository struct {
+		Name  string
+		Owner struct {
+			Login string
+		}
+	}
+	Labels struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 5)"`
+}
+
+// SearchIssues runs a github search for issues and pull requests. after is the EndCursor of the
+// previous page, or empty for the first page.
+func (g *GitHubIssueBot) SearchIssues(ctx context.Context, query string, first int, after string) (*SearchResults, error) {
+	variables := map[string]interface{}{
+		"query": githubv4.String(query),
+		"first": githubv4.Int(first),
+		"after": (*githubv4.String)(nil),
+	}
+	if len(after) != 0 {
+		variables["after"] = githubv4.NewString(githubv4.String(after))
+	}
+	var q struct {
+		Search struct {
+			IssueCount int
+			PageInfo   struct {
+				EndCursor   string
+				HasNextPage bool
+			}
+			Nodes []struct {
+				Typename string `graphql:"__typename"`
+				Issue    struct {
+					searchResultFields
+					State string
+				} `graphql:"... on Issue"`
+				PullRequest struct {
+					searchResultFields
+					State string `graphql:"prState: state"`
+				} `graphql:"... on PullRequest"`
+			}
+		} `graphql:"search(query: $query, type: ISSUE, first: $first, after: $after)"`
+	}
+	if err := g.client.Query(ctx, &q, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+
+	results := &SearchResults{
+		Total:     q.Search.IssueCount,
+		EndCursor: q.Search.PageInfo.EndCursor,
+		HasMore:   q.Search.PageInfo.HasNextPage,
+	}
+	for _, node := range q.Search.Nodes {
+		fields, state := node.Issue.searchResultFields, node.Issue.State
+		if node.Typename == "PullRequest" {
+			fields, state = node.PullRequest.searchResultFields, node.PullRequest.State
+		}
+		details := IssueDetails{
+			Ref:           issueRef{Owner: fields.Repository.Owner.Login, Repo: fields.Repository.Name, Number: fields.Number},
+			Title:         fields.Title,
+			Url:           fields.Url,
+			State:         state,
+			IsPullRequest: node.Typename == "PullRequest",
+		}
+		for _, label := range fields.Labels.Nodes {
+			details.Labels = append(details.Labels, label.Name)
+		}
+		results.Issues = append(results.Issues, details)
+	}
+	return results, nil
+}
+
+// issueID finds the node ID of an issue or pull request.
+func (g *GitHubIssueBot) issueID(ctx context.Context, ref issueRef) (githubv4.ID, error) {
+	variables := map[string]interface{}{
+		"org":    githubv4.String(ref.Owner),
+		"repo":   githubv4.String(ref.Repo),
+		"number": githubv4.Int(ref.Number),
+	}
+	var query struct {
+		Repository struct {
+			IssueOrPullRequest struct {
+				Issue struct {
+					ID githubv4.ID
+				} `graphql:"... on Issue"`
+				PullRequest struct {
+					ID githubv4.ID
+				} `graphql:"... on PullRequest"`
+			} `graphql:"issueOrPullRequest(number: $number)"`
+		} `graphql:"repository(name: $repo, owner: $org)"`
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	result := query.Repository.IssueOrPullRequest
+	if result.Issue.ID != nil {
+		return result.Issue.ID, nil
+	}
+	if result.PullRequest.ID != nil {
+		return result.PullRequest.ID, nil
+	}
+	return nil, trace.NotFound("%v not found", ref)
+}
+
+// AddComment comments on an issue or pull request and returns the comment's url.
+func (g *GitHubIssueBot) AddComment(ctx context.Context, ref issueRef, body string) (string, error) {
+	subjectID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	var m struct {
+		AddComment struct {
+			CommentEdge struct {
+				Node struct {
+					Url string
+				}
+			}
+		} `graphql:"addComment(input: $input)"`
+	}
+	input := githubv4.AddCommentInput{
+		SubjectID: subjectID,
+		Body:      githubv4.String(body),
+	}
+	if err := g.client.Mutate(ctx, &m, input, nil); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return m.AddComment.CommentEdge.Node.Url, nil
+}
+
+// ViewerPermission is the token owner's permission on a repo: ADMIN, MAINTAIN, WRITE, TRIAGE or READ.
+func (g *GitHubIssueBot) ViewerPermission(ctx context.Context, owner, name string) (string, error) {
+	variables := map[string]interface{}{
+		"org":  githubv4.String(owner),
+		"repo": githubv4.String(name),
+	}
+	var query struct {
+		Repository struct {
+			ViewerPermission string
+		} `graphql:"repository(name: $repo, owner: $org)"`
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return query.Repository.ViewerPermission, nil
+}
+
+// CloseIssue closes an issue, optionally with a reason, COMPLETED or NOT_PLANNED, and returns its new state.
+func (g *GitHubIssueBot) CloseIssue(ctx context.Context, ref issueRef, reason string) (string, error) {
+	issueID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	// NOTE: githubv4 doesn't have stateReason yet, and depends on this type name
+	type CloseIssueInput struct {
+		IssueID          githubv4.ID      `json:"issueId"`
+		StateReason      *githubv4.String `json:"stateReason,omitempty"`
+		ClientMutationID *githubv4.String `json:"clientMutationId,omitempty"`
+	}
+	input := CloseIssueInput{IssueID: issueID}
+	if len(reason) != 0 {
+		input.StateReason = githubv4.NewString(githubv4.String(reason))
+	}
+	var m struct {
+		CloseIssue struct {
+			Issue struct {
+				State string
+			}
+		} `graphql:"closeIssue(input: $input)"`
+	}
+	if err := g.client.Mutate(ctx, &m, input, nil); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return m.CloseIssue.Issue.State, nil
+}
+
+// ReopenIssue reopens an issue and returns its new state.
+func (g *GitHubIssueBot) ReopenIssue(ctx context.Context, ref issueRef) (string, error) {
+	issueID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	var m struct {
+		ReopenIssue struct {
+			Issue struct {
+				State string
+			}
+		} `graphql:"reopenIssue(input: $input)"`
+	}
+	if err := g.client.Mutate(ctx, &m, githubv4.ReopenIssueInput{IssueID: issueID}, nil); err != nil {
+		return "", trace.Wrap(err)
+	}
+	return m.ReopenIssue.Issue.State, nil
+}
+
+// LockIssue locks the conversation on an issue or pull request, optionally with a reason.
+func (g *GitHubIssueBot) LockIssue(ctx context.Context, ref issueRef, reason githubv4.LockReason) error {
+	lockableID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	input := githubv4.LockLockableInput{LockableID: lockableID}
+	if len(reason) != 0 {
+		input.LockReason = &reason
+	}
+	var m struct {
+		LockLockable struct {
+			LockedRecord struct {
+				Locked bool
+			}
+		} `graphql:"lockLockable(input: $input)"`
+	}
+	return trace.Wrap(g.client.Mutate(ctx, &m, input, nil))
+}
+
+// IssueChanges are names, of labels or assignees, to add to and remove from an issue.
+type IssueChanges struct {
+	Add    []string
+	Remove []string
+}
+
+// changeIDs looks up the node IDs of changes with lookup. Names it can't find are returned as unknown.
+func changeIDs(changes IssueChanges, lookup func(string) (githubv4.ID, error)) (add, remove []githubv4.ID, unknown []string, err error) {
+	for _, list := range []struct {
+		names []string
+		ids   *[]githubv4.ID
+	}{{changes.Add, &add}, {changes.Remove, &remove}} {
+		for _, name := range list.names {
+			id, err := lookup(name)
+			if err != nil {
+				return nil, nil, nil, trace.Wrap(err)
+			}
+			if id == nil {
+				unknown = append(unknown, name)
+				continue
+			}
+			*list.ids = append(*list.ids, id)
+		}
+	}
+	return add, remove, unknown, nil
+}
+
+// ChangeLabels adds and removes labels on an issue or pull request. Labels the repo doesn't have
+// are skipped and returned.
+func (g *GitHubIssueBot) ChangeLabels(ctx context.Context, ref issueRef, changes IssueChanges) ([]string, error) {
+	labelableID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	add, remove, unknown, err := changeIDs(changes, func(label string) (githubv4.ID, error) {
+		return g.labelID(ctx, ref.Owner, ref.Repo, label)
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(add) != 0 {
+		var m struct {
+			AddLabelsToLabelable struct {
+				Typename string `graphql:"__typename"`
+			} `graphql:"addLabelsToLabelable(input: $input)"`
+		}
+		input := githubv4.AddLabelsToLabelableInput{LabelableID: labelableID, LabelIDs: add}
+		if err := g.client.Mutate(ctx, &m, input, nil); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if len(remove) != 0 {
+		var m struct {
+			RemoveLabelsFromLabelable struct {
+				Typename string `graphql:"__typename"`
+			} `graphql:"removeLabelsFromLabelable(input: $input)"`
+		}
+		input := githubv4.RemoveLabelsFromLabelableInput{LabelableID: labelableID, LabelIDs: remove}
+		if err := g.client.Mutate(ctx, &m, input, nil); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return unknown, nil
+}
+
+// ChangeAssignees adds and removes assignees on an issue or pull request. Logins that can't be
+// assigned on the repo are skipped and returned.
+func (g *GitHubIssueBot) ChangeAssignees(ctx context.Context, ref issueRef, changes IssueChanges) ([]string, error) {
+	assignableID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	add, remove, unknown, err := changeIDs(changes, func(login string) (githubv4.ID, error) {
+		return g.assigneeID(ctx, ref.Owner, ref.Repo, login)
+	})
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if len(add) != 0 {
+		var m struct {
+			AddAssigneesToAssignable struct {
+				Typename string `graphql:"__typename"`
+			} `graphql:"addAssigneesToAssignable(input: $input)"`
+		}
+		input := githubv4.AddAssigneesToAssignableInput{AssignableID: assignableID, AssigneeIDs: add}
+		if err := g.client.Mutate(ctx, &m, input, nil); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	if len(remove) != 0 {
+		var m struct {
+			RemoveAssigneesFromAssignable struct {
+				Typename string `graphql:"__typename"`
+			} `graphql:"removeAssigneesFromAssignable(input: $input)"`
+		}
+		input := githubv4.RemoveAssigneesFromAssignableInput{AssignableID: assignableID, AssigneeIDs: remove}
+		if err := g.client.Mutate(ctx, &m, input, nil); err != nil {
+			return nil, trace.Wrap(err)
+		}
+	}
+	return unknown, nil
+}
+
+// UpdateIssue changes the title or body of an issue. Nil fields are left alone.
+func (g *GitHubIssueBot) UpdateIssue(ctx context.Context, ref issueRef, title, body *string) error {
+	issueID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	input := githubv4.UpdateIssueInput{ID: issueID}
+	if title != nil {
+		input.Title = githubv4.NewString(githubv4.String(*title))
+	}
+	if body != nil {
+		input.Body = githubv4.NewString(githubv4.String(*body))
+	}
+	var m struct {
+		UpdateIssue struct {
+			Issue struct {
+				Url string
+			}
+		} `graphql:"updateIssue(input: $input)"`
+	}
+	return trace.Wrap(g.client.Mutate(ctx, &m, input, nil))
+}
+
+// DeleteIssue deletes an issue. Only repo admins can.
+func (g *GitHubIssueBot) DeleteIssue(ctx context.Context, ref issueRef) error {
+	issueID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var m struct {
+		DeleteIssue struct {
+			Repository struct {
+				Name string
+			}
+		} `graphql:"deleteIssue(input: $input)"`
+	}
+	return trace.Wrap(g.client.Mutate(ctx, &m, githubv4.DeleteIssueInput{IssueID: issueID}, nil))
+}
+
+// TransferIssue moves an issue to another repo with the same owner, and returns it where it is now.
+func (g *GitHubIssueBot) TransferIssue(ctx context.Context, ref issueRef, repo string) (*Issue, error) {
+	issueID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	repositoryID, err := g.repositoryID(ctx, ref.Owner, repo)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// NOTE: githubv4 doesn't have this type yet, and depends on this type name
+	type TransferIssueInput struct {
+		IssueID          githubv4.ID      `json:"issueId"`
+		RepositoryID     githubv4.ID      `json:"repositoryId"`
+		ClientMutationID *githubv4.String `json:"clientMutationId,omitempty"`
+	}
+	var m struct {
+		TransferIssue struct {
+			Issue Issue
+		} `graphql:"transferIssue(input: $input)"`
+	}
+	input := TransferIssueInput{IssueID: issueID, RepositoryID: repositoryID}
+	if err := g.client.Mutate(ctx, &m, input, nil); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return &m.TransferIssue.Issue, nil
+}
+
+// TemplateFile is a file in a repo's issue template directory.
+type TemplateFile struct {
+	Name string
+	Text string
+}
+
+// IssueTemplateFiles reads the files in a repo's .github/ISSUE_TEMPLATE directory on its default branch.
+// A repo without the directory has no files.
+func (g *GitHubIssueBot) IssueTemplateFiles(ctx context.Context, owner, name string) ([]TemplateFile, error) {
+	variables := map[string]interface{}{
+		"org":        githubv4.String(owner),
+		"repo":       githubv4.String(name),
+		"expression": githubv4.String("HEAD:" + templateDir),
+	}
+	var query struct {
+		Repository struct {
+			Object struct {
+				Tree struct {
+					Entries []struct {
+						Name   string
+						Object struct {
+							Blob struct {
+								IsBinary bool
+								Text     string
+							} `graphql:"... on Blob"`
+						}
+					}
+				} `graphql:"... on Tree"`
+			} `graphql:"object(expression: $expression)"`
+		} `graphql:"repository(name: $repo, owner: $org)"`
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var files []TemplateFile
+	for _, entry := range query.Repository.Object.Tree.Entries {
+		if !entry.Object.Blob.IsBinary {
+			files = append(files, TemplateFile{Name: entry.Name, Text: entry.Object.Blob.Text})
+		}
+	}
+	return files, nil
+}
+
+// Project is a Projects (v2) board and the fields its items have.
+type Project struct {
+	ID     githubv4.ID
+	Title  string
+	Fields []ProjectField
+}
+
+// ProjectField is a field of a project. Options are a single select field's, and Iterations are
+// an iteration field's current and upcoming iterations.
+type ProjectField struct {
+	ID         githubv4.ID
+	Name       string
+	DataType   string // TEXT, NUMBER, DATE, SINGLE_SELECT, ITERATION, or one that can't be set, eg TITLE
+	Options    []ProjectOption
+	Iterations []ProjectIteration
+}
+
+// ProjectOption is an option of a single select field.
+type ProjectOption struct {
+	ID   string
+	Name string
+}
+
+// ProjectIteration is an iteration of an iteration field.
+type ProjectIteration struct {
+	ID        string
+	Title     string
+	StartDate string // YYYY-MM-DD
+	Duration  int    // days
+}
+
+// ProjectFieldValue is a value for a project field. Only the one for the field's type is set.
+type ProjectFieldValue struct {
+	Text                 *string  `json:"text,omitempty"`
+	Number               *float64 `json:"number,omitempty"`
+	Date                 *string  `json:"date,omitempty"`
+	SingleSelectOptionID *string  `json:"singleSelectOptionId,omitempty"`
+	IterationID          *string  `json:"iterationId,omitempty"`
+}
+
+// Project looks up an org's or user's project by its number.
+func (g *GitHubIssueBot) Project(ctx context.Context, owner string, number int) (*Project, error) {
+	variables := map[string]interface{}{
+		"owner":  githubv4.String(owner),
+		"number": githubv4.Int(number),
+	}
+	var query struct {
+		RepositoryOwner struct {
+			ProjectOwner struct {
+				ProjectV2 *struct {
+					ID     githubv4.ID
+					Title  string
+					Fields struct {
+						Nodes []struct {
+							Common struct {
+								ID       githubv4.ID
+								Name     string
+								DataType string
+							} `graphql:"... on ProjectV2FieldCommon"`
+							SingleSelect struct {
+								Options []ProjectOption
+							} `graphql:"... on ProjectV2SingleSelectField"`
+							Iteration struct {
+								Configuration struct {
+									Iterations []ProjectIteration
+								}
+							} `graphql:"... on ProjectV2IterationField"`
+						}
+					} `graphql:"fields(first: 50)"`
+				} `graphql:"projectV2(number: $number)"`
+			} `graphql:"... on ProjectV2Owner"`
+		} `graphql:"repositoryOwner(login: $owner)"`
+	}
+	if err := g.client.Query(ctx, &query, variables); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	found := query.RepositoryOwner.ProjectOwner.ProjectV2
+	if found == nil {
+		return nil, trace.NotFound("%v doesn't have a project %v", owner, number)
+	}
+	project := &Project{ID: found.ID, Title: found.Title}
+	for _, node := range found.Fields.Nodes {
+		project.Fields = append(project.Fields, ProjectField{
+			ID:         node.Common.ID,
+			Name:       node.Common.Name,
+			DataType:   node.Common.DataType,
+			Options:    node.SingleSelect.Options,
+			Iterations: node.Iteration.Configuration.Iterations,
+		})
+	}
+	return project, nil
+}
+
+// AddProjectItem adds an issue to a project, and returns the item's ID. Adding an issue that's
+// already in the project returns its item.
+func (g *GitHubIssueBot) AddProjectItem(ctx context.Context, projectID githubv4.ID, ref issueRef) (githubv4.ID, error) {
+	issueID, err := g.issueID(ctx, ref)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	// NOTE: githubv4 doesn't have this type yet, and depends on this type name
+	type AddProjectV2ItemByIdInput struct {
+		ProjectID        githubv4.ID      `json:"projectId"`
+		ContentID        githubv4.ID      `json:"contentId"`
+		ClientMutationID *githubv4.String `json:"clientMutationId,omitempty"`
+	}
+	var m struct {
+		AddProjectV2ItemByID struct {
+			Item struct {
+				ID githubv4.ID
+			}
+		} `graphql:"addProjectV2ItemById(input: $input)"`
+	}
+	input := AddProjectV2ItemByIdInput{ProjectID: projectID, ContentID: issueID}
+	if err := g.client.Mutate(ctx, &m, input, nil); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	return m.AddProjectV2ItemByID.Item.ID, nil
+}
+
+// SetProjectField sets a field of a project item.
+func (g *GitHubIssueBot) SetProjectField(ctx context.Context, projectID, itemID, fieldID githubv4.ID, value ProjectFieldValue) error {
+	// NOTE: githubv4 doesn't have this type yet, and depends on this type name
+	type UpdateProjectV2ItemFieldValueInput struct {
+		ProjectID        githubv4.ID       `json:"projectId"`
+		ItemID           githubv4.ID       `json:"itemId"`
+		FieldID          githubv4.ID       `json:"fieldId"`
+		Value            ProjectFieldValue `json:"value"`
+		ClientMutationID *githubv4.String  `json:"clientMutationId,omitempty"`
+	}
+	var m struct {
+		UpdateProjectV2ItemFieldValue struct {
+			ProjectV2Item struct {
+				ID githubv4.ID
+			}
+		} `graphql:"updateProjectV2ItemFieldValue(input: $input)"`
+	}
+	input := UpdateProjectV2ItemFieldValueInput{ProjectID: projectID, ItemID: itemID, FieldID: fieldID, Value: value}
+	return trace.Wrap(g.client.Mutate(ctx, &m, input, nil))
+}
@@ -1 +1,106 @@
 package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/shurcooL/githubv4"
+	. "gopkg.in/check.v1"
+)
+
+type GitHubSuite struct{}
+
+var _ = Suite(&GitHubSuite{})
+
+// unaliasedStateRegex matches a state field asked for under its own name
+var unaliasedStateRegex = regexp.MustCompile(`[{,]state[,}]`)
+
+// testGitHub is a GitHubIssueBot talking to a server that answers every query with response.
+// The queries it was sent are put in queries.
+func testGitHub(c *C, response string, queries *[]string) (*GitHubIssueBot, func()) {
+	return testGitHubFunc(c, func(query string, variables map[string]interface{}) string {
+		*queries = append(*queries, query)
+		return response
+	})
+}
+
+// testGitHubFunc is a GitHubIssueBot talking to a server that answers each query with respond.
+func testGitHubFunc(c *C, respond func(query string, variables map[string]interface{}) string) (*GitHubIssueBot, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		c.Check(json.NewDecoder(r.Body).Decode(&body), IsNil)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(respond(body.Query, body.Variables)))
+	}))
+	return &GitHubIssueBot{client: githubv4.NewEnterpriseClient(server.URL, server.Client())}, server.Close
+}
+
+func (s *GitHubSuite) TestGetIssueState(c *C) {
+	for _, t := range []struct {
+		typename string
+		state    string
+	}{
+		{typename: "Issue", state: `"state": "CLOSED"`},
+		{typename: "PullRequest", state: `"prState": "MERGED"`},
+	} {
+		var queries []string
+		response := `{"data": {"repository": {"issueOrPullRequest": {"__typename": "` + t.typename + `",
+			"title": "Broken", "url": "https://github.com/gravitational/teleport/issues/1", ` + t.state + `}}}}`
+		bot, done := testGitHub(c, response, &queries)
+		details, err := bot.GetIssue(context.Background(), issueRef{Owner: "gravitational", Repo: "teleport", Number: 1})
+		done()
+		comment := Commentf(t.typename)
+		c.Assert(err, IsNil, comment)
+		c.Assert(queries, HasLen, 1, comment)
+		c.Assert(unaliasedStateRegex.FindAllString(queries[0], -1), HasLen, 1, comment)
+		c.Assert(queries[0], Matches, `.*\.\.\. on PullRequest\{.*,prState: state\}.*`, comment)
+		c.Assert(details.Title, Equals, "Broken", comment)
+		c.Assert(details.State, Equals, map[string]string{"Issue": "CLOSED", "PullRequest": "MERGED"}[t.typename], comment)
+	}
+}
+
+func (s *GitHubSuite) TestSearchIssuesState(c *C) {
+	var queries []string
+	response := `{"data": {"search": {"issueCount": 2, "nodes": [
+		{"__typename": "Issue", "number": 1, "title": "Broken", "state": "OPEN",
+			"repository": {"name": "teleport", "owner": {"login": "gravitational"}}},
+		{"__typename": "PullRequest", "number": 2, "title": "Fix it", "prState": "MERGED",
+			"repository": {"name": "teleport", "owner": {"login": "gravitational"}}}]}}}`
+	bot, done := testGitHub(c, response, &queries)
+	defer done()
+	results, err := bot.SearchIssues(context.Background(), "broken", 5, "")
+	c.Assert(err, IsNil)
+	c.Assert(queries, HasLen, 1)
+	c.Assert(unaliasedStateRegex.FindAllString(queries[0], -1), HasLen, 1)
+	c.Assert(queries[0], Matches, `.*\.\.\. on PullRequest\{.*,prState: state\}.*`)
+	c.Assert(results.Issues, HasLen, 2)
+	c.Assert(results.Issues[0].State, Equals, "OPEN")
+	c.Assert(results.Issues[1].State, Equals, "MERGED")
+	c.Assert(results.Issues[1].Ref, Equals, issueRef{Owner: "gravitational", Repo: "teleport", Number: 2})
+}
+
+func (s *GitHubSuite) TestKnownFields(c *C) {
+	client, done := testGitHubFunc(c, func(query string, variables map[string]interface{}) string {
+		switch {
+		case variables["label"] == "bug":
+			return `{"data": {"repository": {"label": {"id": "L1"}}}}`
+		case variables["label"] != nil:
+			return `{"data": {"repository": {"label": null}}}`
+		case variables["login"] == "ayjayt":
+			return `{"data": {"repository": {"assignableUsers": {"nodes": [{"id": "U1", "login": "ayjayt"}, {"id": "U2", "login": "ayjayt2"}]}}}}`
+		}
+		return `{"data": {"repository": {"assignableUsers": {"nodes": []}}}}`
+	})
+	defer done()
+	fields := IssueFields{Labels: []string{"bug", "bgu"}, Assignees: []string{"ayjayt", "nobody"}, Milestone: "v5"}
+	known, dropped, err := client.KnownFields(context.Background(), "gravitational/teleport", fields)
+	c.Assert(err, IsNil)
+	c.Assert(known, DeepEquals, IssueFields{Labels: []string{"bug"}, Assignees: []string{"ayjayt"}, Milestone: "v5"})
+	c.Assert(dropped, DeepEquals, []string{"label bgu", "assignee @nobody"})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// issueURLRegex finds the owner, repo and number in a github issue or pull request url
+	issueURLRegex = regexp.MustCompile(`^https?://(?:www\.)?github\.com/([\w.-]+)/([\w.-]+)/(?:issues|pull)/(\d+)`)
+	// issueShorthandRegex finds owner/repo#N or repo#N references in conversation
+	issueShorthandRegex = regexp.MustCompile(`(?:^|[^\w/.#-])(?:([\w.-]+)/)?([\w.-]+)#(\d+)\b`)
+	// issueRefRegex matches a whole owner/repo#N or repo#N argument
+	issueRefRegex = regexp.MustCompile(`^(?:([\w.-]+)/)?([\w.-]+)#(\d+)$`)
+)
+
+// issueRef points at an issue or pull request.
+type issueRef struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+// String formats the reference as owner/repo#N.
+func (ref issueRef) String() string {
+	return fmt.Sprintf("%v/%v#%v", ref.Owner, ref.Repo, ref.Number)
+}
+
+// parseIssueURL makes an issueRef from a github issue or pull request url.
+func parseIssueURL(url string) (issueRef, bool) {
+	match := issueURLRegex.FindStringSubmatch(url)
+	if match == nil {
+		return issueRef{}, false
+	}
+	number, err := strconv.Atoi(match[3])
+	if err != nil {
+		return issueRef{}, false
+	}
+	return issueRef{Owner: match[1], Repo: match[2], Number: number}, true
+}
+
+// refFromMatch makes an issueRef from the owner, repo and number groups of a match.
+func refFromMatch(match []string, defaultOwner string) (issueRef, bool) {
+	owner := match[1]
+	if len(owner) == 0 {
+		owner = defaultOwner
+	}
+	number, err := strconv.Atoi(match[3])
+	if err != nil || len(owner) == 0 {
+		return issueRef{}, false
+	}
+	return issueRef{Owner: owner, Repo: match[2], Number: number}, true
+}
+
+// parseIssueRef parses an owner/repo#N command argument. The short form, repo#N, uses defaultOwner.
+// Issue urls, as slack formats them, are accepted too.
+func parseIssueRef(text, defaultOwner string) (issueRef, bool) {
+	text = strings.TrimSpace(text)
+	if ref, ok := parseIssueURL(strings.Trim(text, "<>")); ok {
+		return ref, true
+	}
+	match := issueRefRegex.FindStringSubmatch(text)
+	if match == nil {
+		return issueRef{}, false
+	}
+	return refFromMatch(match, defaultOwner)
+}
+
+// findIssueRefs finds every distinct issue reference in a message.
+func findIssueRefs(text, defaultOwner string) []issueRef {
+	var refs []issueRef
+	seen := make(map[issueRef]bool)
+	for _, match := range issueShorthandRegex.FindAllStringSubmatch(text, -1) {
+		ref, ok := refFromMatch(match, defaultOwner)
+		if !ok || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+	return refs
+}
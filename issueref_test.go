@@ -0,0 +1,50 @@
+package main
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type IssueRefSuite struct{}
+
+var _ = Suite(&IssueRefSuite{})
+
+func (s *IssueRefSuite) TestFindIssueRefs(c *C) {
+	testTables := []struct {
+		name string
+		text string
+		refs []issueRef
+	}{
+		{name: "Long Form",
+			text: "see gravitational/teleport#4521 for details",
+			refs: []issueRef{{Owner: "gravitational", Repo: "teleport", Number: 4521}},
+		},
+		{name: "Short Form",
+			text: "teleport#4521, teleport#4521 and issuebot#7",
+			refs: []issueRef{{Owner: "default", Repo: "teleport", Number: 4521}, {Owner: "default", Repo: "issuebot", Number: 7}},
+		},
+		{name: "Not References",
+			text: "issue #12, <#C1234|general>, <https://example.com/page#1> and a#b",
+			refs: nil,
+		},
+	}
+	for i, tt := range testTables {
+		c.Assert(findIssueRefs(tt.text, "default"), DeepEquals, tt.refs, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
+
+func (s *IssueRefSuite) TestParseIssueRef(c *C) {
+	ref, ok := parseIssueRef(" teleport#12 ", "gravitational")
+	c.Assert(ok, Equals, true)
+	c.Assert(ref, Equals, issueRef{Owner: "gravitational", Repo: "teleport", Number: 12})
+
+	_, ok = parseIssueRef("teleport#12", "")
+	c.Assert(ok, Equals, false)
+
+	ref, ok = parseIssueRef("<https://github.com/gravitational/teleport/issues/56>", "")
+	c.Assert(ok, Equals, true)
+	c.Assert(ref.String(), Equals, "gravitational/teleport#56")
+
+	ref, ok = parseIssueURL("https://github.com/gravitational/teleport/pull/34")
+	c.Assert(ok, Equals, true)
+	c.Assert(ref.String(), Equals, "gravitational/teleport#34")
+}
@@ -3,6 +3,8 @@ package main
 
 import (
 	"context"
+	"flag"
+	"net/http"
 	"os"
 	"os/signal"
 
@@ -25,7 +27,7 @@ func run(ctx context.Context) error {
 		return trace.Wrap(err)
 	}
 
-	slackBot := newSlackBot(cfg.slackToken, cfg.authedUsers)
+	slackBot := newSlackBot(cfg)
 
 	slackBotErr := make(chan error)
 	go func() {
@@ -37,6 +39,18 @@ func run(ctx context.Context) error {
 		}
 	}()
 
+	httpErr := make(chan error)
+	if len(cfg.listen) != 0 {
+		server := slackBot.newHTTPServer(ctx, cfg.listen, cfg.signingSecret)
+		go func() {
+			if err := server.ListenAndServe(); err != http.ErrServerClosed {
+				httpErr <- trace.Wrap(err)
+			}
+		}()
+		defer server.Close()
+		log.Infof("Listening for slash commands on %v", cfg.listen)
+	}
+
 	signalChannel := make(chan os.Signal, 1)
 	signal.Notify(signalChannel, os.Interrupt)
 	log.Infof("Ready to go")
@@ -48,11 +62,25 @@ func run(ctx context.Context) error {
 		// NOTE: context.CancelFunc is a hard kill, it won't acheive the goals of running/WaitGroup
 	case err := <-slackBotErr:
 		return trace.Wrap(err)
+	case err := <-httpErr:
+		return trace.Wrap(err)
 	}
 	return nil
 }
 
 func main() {
+	if flag.Arg(0) == testRulesCommand {
+		var client *GitHubIssueBot
+		if len(*flagGitHubToken) != 0 {
+			client = NewGitHubIssueBot(context.Background(), *flagGitHubToken)
+		}
+		if err := testRules(os.Stdout, *flagRulesFile, client, flag.Args()[1:]); err != nil {
+			log.Errorf("Couldn't test the rules: %v", trace.UserMessage(err))
+			os.Exit(1)
+		}
+		return
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
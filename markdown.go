@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+var (
+	// slackLinkRegex finds a slack link, eg <https://example.com|example> or <mailto:aj@example.com>
+	slackLinkRegex = regexp.MustCompile(`<([a-z][a-z0-9+.-]*:[^|>]+)(?:\|([^>]+))?>`)
+	// slackChannelRegex finds a slack channel link, eg <#C1234ABCD|general>
+	slackChannelRegex = regexp.MustCompile(`<#[A-Z0-9]+(?:\|([^>]*))?>`)
+	// slackSpecialRegex finds slack's special mentions, eg <!here> or <!subteam^S123|@team>
+	slackSpecialRegex = regexp.MustCompile(`<!([^|>]+)(?:\|([^>]*))?>`)
+	// slackUserRegex finds the slack user mentions that the directory couldn't convert
+	slackUserRegex = regexp.MustCompile(`<@([UW][A-Z0-9]+)(?:\|([^>]*))?>`)
+	// slackBoldRegex finds *bold* text
+	slackBoldRegex = regexp.MustCompile(`(^|[\s(])\*([^*\n]+)\*($|[\s).,!?:;])`)
+	// slackStrikeRegex finds ~struck~ text
+	slackStrikeRegex = regexp.MustCompile(`(^|[\s(])~([^~\n]+)~($|[\s).,!?:;])`)
+	// slackEntities are the only characters slack escapes
+	slackEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+	// attributionRegex finds an attribution at the end of a body
+	attributionRegex = regexp.MustCompile(`(?:^|\n)_\w+ from (?:\[Slack\]\([^)\n]*\)|Slack) by [^\n]*_\s*$`)
+)
+
+// toGitHub converts a slack message to github markdown, including mentions of users in the directory.
+func (s *SlackBot) toGitHub(text string) string {
+	return slackToMarkdown(s.directory.ToGitHub(text))
+}
+
+// slackToMarkdown converts slack's mrkdwn to github markdown. Code is left alone.
+func slackToMarkdown(text string) string {
+	blocks := strings.Split(text, "```")
+	for i := 0; i < len(blocks); i += 2 {
+		spans := strings.Split(blocks[i], "`")
+		for j := 0; j < len(spans); j += 2 {
+			spans[j] = convertMrkdwn(spans[j])
+		}
+		blocks[i] = strings.Join(spans, "`")
+	}
+	return slackEntities.Replace(strings.Join(blocks, "```"))
+}
+
+// convertMrkdwn converts text that isn't code.
+func convertMrkdwn(text string) string {
+	text = slackLinkRegex.ReplaceAllStringFunc(text, func(link string) string {
+		match := slackLinkRegex.FindStringSubmatch(link)
+		if len(match[2]) == 0 {
+			return strings.TrimPrefix(match[1], "mailto:")
+		}
+		return fmt.Sprintf("[%v](%v)", match[2], match[1])
+	})
+	text = slackChannelRegex.ReplaceAllString(text, "#$1")
+	// Mentions that github would take for its own users are put in code
+	text = slackSpecialRegex.ReplaceAllStringFunc(text, func(special string) string {
+		match := slackSpecialRegex.FindStringSubmatch(special)
+		if len(match[2]) != 0 {
+			return "`" + match[2] + "`"
+		}
+		return "`@" + match[1] + "`"
+	})
+	text = slackUserRegex.ReplaceAllStringFunc(text, func(mention string) string {
+		match := slackUserRegex.FindStringSubmatch(mention)
+		if len(match[2]) != 0 {
+			return "`@" + match[2] + "`"
+		}
+		return "`@" + match[1] + "`"
+	})
+	// Run twice so that adjacent matches, which share a space, are both found
+	for i := 0; i < 2; i++ {
+		text = slackBoldRegex.ReplaceAllString(text, "$1**$2**$3")
+		text = slackStrikeRegex.ReplaceAllString(text, "$1~~$2~~$3")
+	}
+	return text
+}
+
+// attribution is the footer on everything the bot writes to github for someone, eg "Filed" or "Commented".
+func attribution(action, link, author string) string {
+	if len(link) == 0 {
+		return fmt.Sprintf("_%v from Slack by %v_", action, author)
+	}
+	return fmt.Sprintf("_%v from [Slack](%v) by %v_", action, link, author)
+}
+
+// withAttribution ends a new issue's body with its attribution, unless it already has one, eg when a
+// preview that was filled into the form is filed from it.
+func withAttribution(body, footer string) string {
+	if attributionRegex.MatchString(body) {
+		return body
+	}
+	if len(strings.TrimSpace(body)) == 0 {
+		return footer
+	}
+	return body + "\n\n" + footer
+}
+
+// userAttribution is the attribution for something written to github from a form or a DM, which has
+// no message worth linking to.
+func (s *SlackBot) userAttribution(ctx context.Context, user, action string) string {
+	return attribution(action, "", s.authorName(ctx, slack.Message{Msg: slack.Msg{User: user}}, map[string]string{}))
+}
+
+// commandAttribution is the attribution for something written to github by a command, linking to its message.
+func (s *SlackBot) commandAttribution(ctx context.Context, r request, action string) string {
+	event := r.Event()
+	var link string
+	if len(event.Timestamp) != 0 { // slash commands don't have a message to link to
+		var err error
+		link, err = s.sBot.Client().GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: event.Channel, Ts: event.Timestamp})
+		if err != nil {
+			log.Infof("couldn't get permalink for %v: %v", event.Timestamp, err)
+		}
+	}
+	return attribution(action, link, s.authorName(ctx, slack.Message{Msg: event.Msg}, map[string]string{}))
+}
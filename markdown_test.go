@@ -0,0 +1,59 @@
+package main
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type MarkdownSuite struct{}
+
+var _ = Suite(&MarkdownSuite{})
+
+func (s *MarkdownSuite) TestSlackToMarkdown(c *C) {
+	testTables := []struct {
+		name     string
+		mrkdwn   string
+		markdown string
+	}{
+		{name: "Formatting",
+			mrkdwn:   "*bold* and _italic_, ~struck~ *twice* *bold*",
+			markdown: "**bold** and _italic_, ~~struck~~ **twice** **bold**",
+		},
+		{name: "Links",
+			mrkdwn:   "see <https://example.com/a?b=1&amp;c=2|the docs>, <https://example.com> or <mailto:aj@example.com|aj@example.com>",
+			markdown: "see [the docs](https://example.com/a?b=1&c=2), https://example.com or [aj@example.com](mailto:aj@example.com)",
+		},
+		{name: "Mentions",
+			mrkdwn:   "<!here> in <#C1234ABCD|general>, cc <@U9999ZZZZ|someone> <@U9999ZZZZ>",
+			markdown: "`@here` in #general, cc `@someone` `@U9999ZZZZ`",
+		},
+		{name: "Code",
+			mrkdwn:   "run `*x*` then\n```a &lt; *b*```",
+			markdown: "run `*x*` then\n```a < *b*```",
+		},
+	}
+	for i, tt := range testTables {
+		c.Assert(slackToMarkdown(tt.mrkdwn), Equals, tt.markdown, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
+
+func (s *MarkdownSuite) TestAttribution(c *C) {
+	c.Assert(attribution("Commented", "https://slack.example/p1", "AJ (@ayjayt)"), Equals, "_Commented from [Slack](https://slack.example/p1) by AJ (@ayjayt)_")
+	c.Assert(attribution("Commented", "", "AJ"), Equals, "_Commented from Slack by AJ_")
+}
+
+func (s *MarkdownSuite) TestWithAttribution(c *C) {
+	footer := attribution("Filed", "https://slack.example/p1", "AJ (@ayjayt)")
+	testTables := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "Body", body: "It hangs", want: "It hangs\n\n" + footer},
+		{name: "Empty", body: " \n", want: footer},
+		{name: "Already Attributed", body: "It hangs\n\n_Filed from Slack by AJ_\n", want: "It hangs\n\n_Filed from Slack by AJ_\n"},
+		{name: "Attribution In The Middle", body: "_Filed from Slack by AJ_\n\nmore", want: "_Filed from Slack by AJ_\n\nmore\n\n" + footer},
+	}
+	for i, tt := range testTables {
+		c.Assert(withAttribution(tt.body, footer), Equals, tt.want, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
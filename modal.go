@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+const (
+	// newIssueCallbackID identifies the new issue modal's submissions
+	newIssueCallbackID = "new_issue"
+	// issueShortcutCallbackID is the callback ID of the "Create GitHub issue" message shortcut,
+	// as it's set up in the slack app
+	issueShortcutCallbackID = "create_github_issue"
+	// maxRepoOptions is the most options slack allows in a select
+	maxRepoOptions = 100
+	// RepoOptionsTimeoutMilliseconds is how long the bot waits for a user's repos when they're choosing one
+	// in the modal. Slack waits 3 seconds for the options.
+	RepoOptionsTimeoutMilliseconds = 2000
+	slackAPIURL                    = "https://slack.com/api/"
+)
+
+// NOTE: nlopes/slack doesn't support modals yet, so the following are the parts of
+// Block Kit's view objects that issuebot uses. See https://api.slack.com/reference/surfaces/views
+
+// textObject is a Block Kit text composition object.
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// plainText returns a plain_text textObject.
+func plainText(text string) *textObject {
+	return &textObject{Type: "plain_text", Text: text}
+}
+
+// optionObject is an option in a select menu.
+type optionObject struct {
+	Text  *textObject `json:"text"`
+	Value string      `json:"value"`
+}
+
+// blockElement is an interactive element in an input block. Only the fields for its Type are set.
+type blockElement struct {
+	Type          string          `json:"type"`
+	ActionID      string          `json:"action_id"`
+	Placeholder   *textObject     `json:"placeholder,omitempty"`
+	InitialValue  string          `json:"initial_value,omitempty"`
+	Multiline     bool            `json:"multiline,omitempty"`
+	Options       []*optionObject `json:"options,omitempty"`
+	InitialOption *optionObject   `json:"initial_option,omitempty"`
+	InitialUsers  []string        `json:"initial_users,omitempty"`
+	// MinQueryLength is how much has to be typed in an external select before slack asks for its options
+	MinQueryLength *int `json:"min_query_length,omitempty"`
+}
+
+// inputBlock is a Block Kit input block.
+type inputBlock struct {
+	Type     string        `json:"type"`
+	BlockID  string        `json:"block_id"`
+	Label    *textObject   `json:"label"`
+	Element  *blockElement `json:"element"`
+	Hint     *textObject   `json:"hint,omitempty"`
+	Optional bool          `json:"optional,omitempty"`
+}
+
+// newInputBlock returns an input block whose element's action_id is the same as its block_id.
+func newInputBlock(id, label string, optional bool, element *blockElement) *inputBlock {
+	element.ActionID = id
+	return &inputBlock{Type: "input", BlockID: id, Label: plainText(label), Element: element, Optional: optional}
+}
+
+// view is a Block Kit modal.
+type view struct {
+	Type            string        `json:"type"`
+	CallbackID      string        `json:"callback_id"`
+	PrivateMetadata string        `json:"private_metadata,omitempty"`
+	Title           *textObject   `json:"title"`
+	Submit          *textObject   `json:"submit,omitempty"`
+	Close           *textObject   `json:"close,omitempty"`
+	Blocks          []interface{} `json:"blocks"`
+}
+
+// viewState is a submitted modal, as slack sends it in a view_submission.
+type viewState struct {
+	ID              string `json:"id"`
+	CallbackID      string `json:"callback_id"`
+	PrivateMetadata string `json:"private_metadata"`
+	State           struct {
+		// Values are keyed by block_id then action_id
+		Values map[string]map[string]stateValue `json:"values"`
+	} `json:"state"`
+}
+
+// stateValue is the value of one input in a submitted modal.
+type stateValue struct {
+	Type           string         `json:"type"`
+	Value          string         `json:"value"`
+	SelectedOption *optionObject  `json:"selected_option"`
+	SelectedUsers  []string       `json:"selected_users"`
+	SelectedOpts   []optionObject `json:"selected_options"`
+}
+
+// value returns what was entered in the input whose block_id and action_id are id.
+func (v *viewState) value(id string) stateValue {
+	return v.State.Values[id][id]
+}
+
+// text returns the text of a text input or the value of a static select.
+func (sv stateValue) text() string {
+	if sv.SelectedOption != nil {
+		return sv.SelectedOption.Value
+	}
+	return strings.TrimSpace(sv.Value)
+}
+
+// modalMetadata is kept in a modal's private_metadata so the submission knows where it came from.
+type modalMetadata struct {
+	// Channel is where the modal was opened from, and where the result is posted
+	Channel string `json:"channel,omitempty"`
+	// Pending is the pending issue a template modal fills in
+	Pending string `json:"pending,omitempty"`
+	// Thread is linked to the issue once it's filed
+	Thread string `json:"thread,omitempty"`
+	// Assignees are github logins to assign that have no slack user, so the form can't show them
+	Assignees []string `json:"assignees,omitempty"`
+}
+
+// issueDraft is what a new issue modal is prefilled with.
+type issueDraft struct {
+	Repo   string
+	Title  string
+	Body   string
+	Fields IssueFields
+	// Thread is linked to the issue once it's filed
+	Thread string
+}
+
+// slackAPI calls a slack web API method with a JSON payload, and decodes the reply into response if it
+// isn't nil. It's for methods nlopes/slack doesn't have yet.
+func (s *SlackBot) slackAPI(ctx context.Context, method string, payload, response interface{}) error {
+	contents, err := json.Marshal(payload)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req, err := http.NewRequest("POST", slackAPIURL+method, bytes.NewReader(contents))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	reply, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var result struct {
+		Ok       bool   `json:"ok"`
+		Error    string `json:"error"`
+		Metadata struct {
+			Messages []string `json:"messages"`
+		} `json:"response_metadata"`
+	}
+	if err := json.Unmarshal(reply, &result); err != nil {
+		return trace.Wrap(err)
+	}
+	if !result.Ok {
+		return trace.Errorf("%v: %v %v", method, result.Error, strings.Join(result.Metadata.Messages, "; "))
+	}
+	if response != nil {
+		return trace.Wrap(json.Unmarshal(reply, response))
+	}
+	return nil
+}
+
+// viewResponse is the reply to views.open and views.update.
+type viewResponse struct {
+	View struct {
+		ID   string `json:"id"`
+		Hash string `json:"hash"`
+	} `json:"view"`
+}
+
+// issueModal is the new issue modal, prefilled with draft. Its repo is an external select, so any repo
+// can be chosen, see repoOptions. Assignees without a slack user are kept in the metadata.
+func (s *SlackBot) issueModal(channel string, draft issueDraft) (view, error) {
+	noQuery := 0
+	repoElement := &blockElement{Type: "external_select", Placeholder: plainText("Choose a repo or type owner/repo"), MinQueryLength: &noQuery}
+	if repo := s.fullRepo(channel, draft.Repo); len(repo) != 0 {
+		repoElement.InitialOption = &optionObject{Text: plainText(repo), Value: repo}
+	}
+	assignees := &blockElement{Type: "multi_users_select", Placeholder: plainText("Slack users with a GitHub login")}
+	var hidden []string
+	for _, login := range draft.Fields.Assignees {
+		if user, ok := s.directory.SlackUser(login); ok {
+			assignees.InitialUsers = append(assignees.InitialUsers, user)
+		} else {
+			hidden = append(hidden, login)
+		}
+	}
+	metadata, err := json.Marshal(modalMetadata{Channel: channel, Thread: draft.Thread, Assignees: hidden})
+	if err != nil {
+		return view{}, trace.Wrap(err)
+	}
+	assigneesBlock := newInputBlock("assignees", "Assignees", true, assignees)
+	if len(hidden) != 0 {
+		assigneesBlock.Hint = plainText("Also assigned: @" + strings.Join(hidden, ", @"))
+	}
+	return view{
+		Type:            "modal",
+		CallbackID:      newIssueCallbackID,
+		PrivateMetadata: string(metadata),
+		Title:           plainText("New GitHub issue"),
+		Submit:          plainText("Create"),
+		Close:           plainText("Cancel"),
+		Blocks: []interface{}{
+			newInputBlock("repo", "Repository", false, repoElement),
+			newInputBlock("title", "Title", false, &blockElement{Type: "plain_text_input", InitialValue: draft.Title}),
+			newInputBlock("body", "Description", true, &blockElement{Type: "plain_text_input", Multiline: true, InitialValue: draft.Body}),
+			newInputBlock("labels", "Labels", true, &blockElement{Type: "plain_text_input", Placeholder: plainText("bug, needs-triage"),
+				InitialValue: strings.Join(draft.Fields.Labels, ", ")}),
+			assigneesBlock,
+		},
+	}, nil
+}
+
+// fullRepo adds the channel's default owner to a repo that doesn't have one.
+func (s *SlackBot) fullRepo(channel, repo string) string {
+	if len(repo) == 0 || strings.Contains(repo, "/") {
+		return repo
+	}
+	if owner := s.DefaultOwner(channel); len(owner) != 0 {
+		return owner + "/" + repo
+	}
+	return repo
+}
+
+// repoOptions are the repos offered when a user types typed into the modal's repo select: their own
+// repos that match, after what they typed if it's a repo, so repos that aren't listed can be chosen too.
+func repoOptions(repos []string, typed string) []*optionObject {
+	var options []*optionObject
+	if _, _, err := splitRepo(typed); err == nil && !strings.ContainsAny(typed, " \n") {
+		options = append(options, &optionObject{Text: plainText(typed), Value: typed})
+	}
+	lower := strings.ToLower(typed)
+	for _, repo := range repos {
+		if len(options) == maxRepoOptions {
+			break
+		}
+		if strings.Contains(strings.ToLower(repo), lower) && !strings.EqualFold(repo, typed) {
+			options = append(options, &optionObject{Text: plainText(repo), Value: repo})
+		}
+	}
+	return options
+}
+
+// suggestRepos answers the block_suggestion for the modal's repo select.
+func (s *SlackBot) suggestRepos(ctx context.Context, payload *interaction) map[string]interface{} {
+	var metadata modalMetadata
+	if err := json.Unmarshal([]byte(payload.View.PrivateMetadata), &metadata); err != nil {
+		log.Infof("bad modal metadata %q: %v", payload.View.PrivateMetadata, err)
+	}
+	typed := s.fullRepo(metadata.Channel, strings.TrimSpace(payload.Value))
+	var repos []string
+	if client := s.getGBotForUser(ctx, payload.User.ID); client != nil {
+		subCtx, cancel := context.WithTimeout(ctx, time.Millisecond*RepoOptionsTimeoutMilliseconds)
+		defer cancel()
+		var err error
+		if repos, err = client.Repositories(subCtx, maxRepoOptions); err != nil {
+			log.Infof("couldn't list repos for modal: %v", err)
+		}
+	}
+	return map[string]interface{}{"options": repoOptions(repos, typed)}
+}
+
+// openIssueModal opens the new issue modal for a user, prefilled with draft. Slack's trigger_id expires
+// after 3 seconds, so the modal is opened straight away, and whatever fill adds to the draft, if it
+// isn't nil, is filled in afterwards.
+func (s *SlackBot) openIssueModal(ctx context.Context, triggerID, user, channel string, draft issueDraft,
+	fill func(context.Context, issueDraft) issueDraft) error {
+	if s.getGBotForUser(ctx, user) == nil {
+		return trace.AccessDenied("You must register first, see `help` command")
+	}
+	modal, err := s.issueModal(channel, draft)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	var opened viewResponse
+	err = s.slackAPI(ctx, "views.open", map[string]interface{}{
+		"trigger_id": triggerID,
+		"view":       modal,
+	}, &opened)
+	if err != nil || fill == nil {
+		return trace.Wrap(err)
+	}
+
+	go func() {
+		if !s.Begin() {
+			return
+		}
+		defer s.Done()
+		subCtx, cancel := context.WithTimeout(ctx, time.Second*TimeoutSeconds)
+		defer cancel()
+		modal, err := s.issueModal(channel, fill(subCtx, draft))
+		if err != nil {
+			log.Errorf("couldn't build modal: %v", trace.DebugReport(err))
+			return
+		}
+		// The hash stops this overwriting the modal if it's been changed since it was opened
+		err = s.slackAPI(subCtx, "views.update", map[string]interface{}{
+			"view_id": opened.View.ID,
+			"hash":    opened.View.Hash,
+			"view":    modal,
+		}, nil)
+		if err != nil {
+			log.Errorf("couldn't fill in modal: %v", trace.DebugReport(err))
+		}
+	}()
+	return nil
+}
+
+// openShortcutModal opens the new issue modal from the message shortcut, prefilled with the message.
+// The permalink and author are looked up after it's open.
+func (s *SlackBot) openShortcutModal(ctx context.Context, payload *interaction) error {
+	channel := payload.Channel.ID
+	message := payload.Message
+	text := s.toGitHub(message.Text)
+	draft := issueDraft{
+		Repo:  s.settings.Channels[channel].Repo,
+		Title: messageTitle(s.directory.ToGitHub(message.Text)),
+		Body:  text,
+	}
+	fill := func(ctx context.Context, draft issueDraft) issueDraft {
+		link, err := s.sBot.Client().GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: channel, Ts: message.Timestamp})
+		if err != nil {
+			log.Errorf("couldn't get permalink for shortcut: %v", trace.DebugReport(err))
+		}
+		// The footer names who wrote the message, and submitIssueModal leaves it alone
+		draft.Body = withAttribution(text, attribution("Filed", link, s.authorName(ctx, message, map[string]string{})))
+		return draft
+	}
+	return trace.Wrap(s.openIssueModal(ctx, payload.TriggerID, payload.User.ID, channel, draft, fill))
+}
+
+// submitIssueModal files the issue from a submitted new issue modal. It returns validation errors
+// by block_id, if there are any. Otherwise the issue is filed after the modal closes, because
+// slack only waits 3 seconds for a response.
+func (s *SlackBot) submitIssueModal(ctx context.Context, user string, submitted *viewState) map[string]string {
+	repo := submitted.value("repo").text()
+	title := submitted.value("title").text()
+	validation := make(map[string]string)
+	if _, _, err := splitRepo(repo); err != nil {
+		validation["repo"] = "Use the form owner/repo"
+	}
+	if len(title) == 0 {
+		validation["title"] = "An issue needs a title"
+	}
+	if len(validation) != 0 {
+		return validation
+	}
+
+	var metadata modalMetadata
+	if err := json.Unmarshal([]byte(submitted.PrivateMetadata), &metadata); err != nil {
+		log.Infof("bad modal metadata %q: %v", submitted.PrivateMetadata, err)
+	}
+	fields := IssueFields{Labels: splitList(submitted.value("labels").text()), Assignees: metadata.Assignees}
+	var unknown []string
+	for _, assignee := range submitted.value("assignees").SelectedUsers {
+		if login, ok := s.directory.Login(assignee); ok {
+			fields.Assignees = append(fields.Assignees, login)
+		} else {
+			unknown = append(unknown, "<@"+assignee+">")
+		}
+	}
+	body := s.directory.ToGitHub(submitted.value("body").text())
+
+	go func() {
+		if !s.Begin() {
+			return
+		}
+		defer s.Done()
+		var result string
+		client := s.getGBotForUser(ctx, user)
+		if client == nil {
+			result = "You must register first, see `help` command"
+		} else {
+			subCtx, cancel := context.WithTimeout(ctx, time.Second*TimeoutSeconds)
+			defer cancel()
+			body := withAttribution(body, s.userAttribution(subCtx, user, "Filed"))
+			issue, err := client.NewIssue(subCtx, repo, title, body, fields)
+			if err != nil {
+				log.Infof("modal issue error: %v", trace.DebugReport(err))
+				result = fmt.Sprintf("Couldn't create your issue in %v: %v", repo, trace.UserMessage(err))
+			} else {
+				s.recordIssue(user, metadata.Channel, metadata.Thread, issue)
+				result = issue.Url
+			}
+		}
+		if len(unknown) != 0 {
+			result += fmt.Sprintf("\nNot assigned because I don't know their GitHub login: %v", strings.Join(unknown, ", "))
+		}
+		s.notify(user, metadata.Channel, result)
+	}()
+	return nil
+}
+
+// notify tells a user something privately, in channel if there is one, otherwise by DM.
+func (s *SlackBot) notify(user, channel, text string) {
+	api := s.sBot.Client()
+	var err error
+	if len(channel) != 0 {
+		_, err = api.PostEphemeral(channel, user, slack.MsgOptionText(text, false))
+	} else {
+		_, _, err = api.PostMessage(user, slack.MsgOptionText(text, false))
+	}
+	if err != nil {
+		log.Errorf("couldn't notify %v: %v", user, trace.DebugReport(err))
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type ModalSuite struct{}
+
+var _ = Suite(&ModalSuite{})
+
+func (s *ModalSuite) TestIssueModal(c *C) {
+	testTables := []struct {
+		name    string
+		repo    string
+		initial string
+	}{
+		{name: "No Repo", repo: ""},
+		{name: "Full Repo", repo: "gravitational/issuebot", initial: "gravitational/issuebot"},
+		{name: "Channel Owner", repo: "teleport", initial: "gravitational/teleport"},
+	}
+	bot := &SlackBot{org: "gravitational"}
+	for i, tt := range testTables {
+		comment := Commentf("test #%d (%v)", i+1, tt.name)
+		modal, err := bot.issueModal("C0123ABCD", issueDraft{Repo: tt.repo, Title: "tsh hangs", Body: "on login"})
+		c.Assert(err, IsNil, comment)
+		c.Assert(modal.PrivateMetadata, Equals, `{"channel":"C0123ABCD"}`, comment)
+		var ids []string
+		for _, block := range modal.Blocks {
+			ids = append(ids, block.(*inputBlock).BlockID)
+		}
+		c.Assert(ids, DeepEquals, []string{"repo", "title", "body", "labels", "assignees"}, comment)
+		repo := modal.Blocks[0].(*inputBlock).Element
+		c.Assert(repo.Type, Equals, "external_select", comment)
+		c.Assert(*repo.MinQueryLength, Equals, 0, comment)
+		if len(tt.initial) == 0 {
+			c.Assert(repo.InitialOption, IsNil, comment)
+		} else {
+			c.Assert(repo.InitialOption.Value, Equals, tt.initial, comment)
+		}
+		c.Assert(modal.Blocks[2].(*inputBlock).Element.InitialValue, Equals, "on login", comment)
+	}
+}
+
+func (s *ModalSuite) TestRepoOptions(c *C) {
+	repos := []string{"gravitational/teleport", "gravitational/issuebot", "ayjayt/teleport-notes"}
+	testTables := []struct {
+		name    string
+		typed   string
+		options []string
+	}{
+		{name: "Nothing Typed", typed: "", options: repos},
+		{name: "Filtered", typed: "TELE", options: []string{"gravitational/teleport", "ayjayt/teleport-notes"}},
+		{name: "Unlisted Repo", typed: "gravitational/gravity", options: []string{"gravitational/gravity"}},
+		{name: "Listed Repo", typed: "gravitational/teleport", options: []string{"gravitational/teleport"}},
+		{name: "No Match", typed: "nothing", options: nil},
+	}
+	for i, tt := range testTables {
+		var values []string
+		for _, option := range repoOptions(repos, tt.typed) {
+			values = append(values, option.Value)
+		}
+		c.Assert(values, DeepEquals, tt.options, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+	many := make([]string, maxRepoOptions+10)
+	for i := range many {
+		many[i] = fmt.Sprintf("gravitational/repo%v", i)
+	}
+	c.Assert(repoOptions(many, "gravitational/repo"), HasLen, maxRepoOptions)
+}
+
+func (s *ModalSuite) TestIssueModalFields(c *C) {
+	directory, err := newUserDirectory(filepath.Join(c.MkDir(), "directory"))
+	c.Assert(err, IsNil)
+	c.Assert(directory.Set("U1234ABCD", "ayjayt", false), IsNil)
+	bot := &SlackBot{directory: directory}
+
+	// Eg a preview being edited, with the labels and assignees the rules added
+	draft := issueDraft{Repo: "gravitational/teleport", Title: "tsh hangs", Thread: "1549412640.000200",
+		Fields: IssueFields{Labels: []string{"tsh", "bug"}, Assignees: []string{"ayjayt", "stranger"}}}
+	modal, err := bot.issueModal("C0123ABCD", draft)
+	c.Assert(err, IsNil)
+	c.Assert(modal.Blocks[3].(*inputBlock).Element.InitialValue, Equals, "tsh, bug")
+	assignees := modal.Blocks[4].(*inputBlock)
+	c.Assert(assignees.Element.InitialUsers, DeepEquals, []string{"U1234ABCD"})
+	c.Assert(assignees.Hint.Text, Equals, "Also assigned: @stranger")
+	c.Assert(modal.PrivateMetadata, Equals, `{"channel":"C0123ABCD","thread":"1549412640.000200","assignees":["stranger"]}`)
+}
+
+func (s *ModalSuite) TestStateValueText(c *C) {
+	testTables := []struct {
+		name  string
+		value stateValue
+		text  string
+	}{
+		{name: "Text Input", value: stateValue{Type: "plain_text_input", Value: "  tsh hangs \n"}, text: "tsh hangs"},
+		{name: "Static Select", value: stateValue{Type: "static_select", SelectedOption: &optionObject{Value: "gravitational/teleport"}}, text: "gravitational/teleport"},
+		{name: "Nothing Selected", value: stateValue{Type: "static_select"}, text: ""},
+		{name: "Empty", text: ""},
+	}
+	for i, tt := range testTables {
+		c.Assert(tt.value.text(), Equals, tt.text, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
+
+func (s *ModalSuite) TestSubmitIssueModalValidation(c *C) {
+	directory, err := newUserDirectory(filepath.Join(c.MkDir(), "directory"))
+	c.Assert(err, IsNil)
+	// Not running, so a valid submission isn't filed
+	bot := &SlackBot{directory: directory}
+
+	submission := func(repo stateValue, title string) *viewState {
+		submitted := &viewState{CallbackID: newIssueCallbackID, PrivateMetadata: `{"channel":"C0123ABCD"}`}
+		submitted.State.Values = map[string]map[string]stateValue{
+			"repo":  {"repo": repo},
+			"title": {"title": {Type: "plain_text_input", Value: title}},
+		}
+		return submitted
+	}
+	typed := func(repo string) stateValue { return stateValue{Type: "plain_text_input", Value: repo} }
+	testTables := []struct {
+		name       string
+		submitted  *viewState
+		validation map[string]string
+	}{
+		{name: "Valid", submitted: submission(typed("gravitational/teleport"), "tsh hangs")},
+		{name: "Selected Repo", submitted: submission(stateValue{Type: "static_select", SelectedOption: &optionObject{Value: "gravitational/teleport"}}, "tsh hangs")},
+		{name: "No Owner", submitted: submission(typed("teleport"), "tsh hangs"), validation: map[string]string{"repo": "Use the form owner/repo"}},
+		{name: "Blank Title", submitted: submission(typed("gravitational/teleport"), "  "), validation: map[string]string{"title": "An issue needs a title"}},
+		{name: "Empty", submitted: &viewState{}, validation: map[string]string{"repo": "Use the form owner/repo", "title": "An issue needs a title"}},
+	}
+	for i, tt := range testTables {
+		validation := bot.submitIssueModal(context.Background(), "U1234ABCD", tt.submitted)
+		c.Assert(validation, DeepEquals, tt.validation, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
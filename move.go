@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+)
+
+var (
+	// ErrOtherOwner is reported when an issue would move to a repo with another owner, which github doesn't allow
+	ErrOtherOwner = errors.New("Issues can only move between repos with the same owner")
+	// writePermissions are the repo permissions that can move issues
+	writePermissions = map[string]bool{"ADMIN": true, "MAINTAIN": true, "WRITE": true}
+)
+
+// moveIssue is the callback for the "move" command, which transfers an issue to another repo.
+func (s *SlackBot) moveIssue(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	target := strings.TrimSpace(strings.TrimPrefix(r.StringParam("args", ""), "to "))
+	if len(target) == 0 || strings.ContainsAny(target, " \t") {
+		w.ReportError(errors.New("Say where to move it, eg move teleport#4521 to other-repo"))
+		return
+	}
+	from, ok := s.commandIssue(r)
+	if !ok {
+		w.ReportError(ErrNoIssue)
+		return
+	}
+	repo := target
+	if owner, name, err := splitRepo(target); err == nil {
+		if !strings.EqualFold(owner, from.Owner) {
+			w.ReportError(ErrOtherOwner)
+			return
+		}
+		repo = name
+	}
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	for _, name := range []string{from.Repo, repo} {
+		permission, err := client.ViewerPermission(subCtx, from.Owner, name)
+		if err != nil {
+			w.ReportError(fmt.Errorf("Couldn't find %v/%v", from.Owner, name))
+			log.Infof("permission error: %v", trace.DebugReport(err))
+			return
+		}
+		if !writePermissions[permission] {
+			w.ReportError(fmt.Errorf("You need write permission on %v/%v", from.Owner, name))
+			return
+		}
+	}
+	issue, err := client.TransferIssue(subCtx, from, repo)
+	if err != nil {
+		w.ReportError(fmt.Errorf("Couldn't move %v to %v/%v", from, from.Owner, repo))
+		log.Infof("move error: %v", trace.DebugReport(err))
+		return
+	}
+
+	to := issue.Ref()
+	s.relinkThreads(from, to)
+	var creator string
+	if ok, _ := s.creators.Get(from.String(), &creator); ok {
+		if err := s.creators.Put(to.String(), creator); err != nil {
+			log.Errorf(trace.DebugReport(err))
+		}
+		if err := s.creators.Delete(from.String()); err != nil {
+			log.Errorf(trace.DebugReport(err))
+		}
+	}
+	s.audit(event.User, "move", from, to.String())
+	s.replyInThread(r, w, fmt.Sprintf("Moved %v to %v", from, issue.Url))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type MoveSuite struct {
+	dir string
+}
+
+var _ = Suite(&MoveSuite{})
+
+func (s *MoveSuite) SetUpTest(c *C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "issuebot")
+	c.Assert(err, IsNil)
+}
+
+func (s *MoveSuite) TearDownTest(c *C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *MoveSuite) TestRelinkThreads(c *C) {
+	threads, err := newFileStore(filepath.Join(s.dir, "threads"))
+	c.Assert(err, IsNil)
+	bot := &SlackBot{threads: threads}
+	from := issueRef{Owner: "gravitational", Repo: "teleport", Number: 4521}
+	to := issueRef{Owner: "gravitational", Repo: "teleport-plugins", Number: 12}
+	other := issueRef{Owner: "gravitational", Repo: "teleport", Number: 7}
+	bot.linkThread("C1", "1549412640.000200", from)
+	bot.linkThread("C2", "1549412699.000100", from)
+	bot.linkThread("C1", "1549412700.000300", other)
+
+	bot.relinkThreads(from, to)
+	for _, thread := range []struct{ channel, ts string }{{"C1", "1549412640.000200"}, {"C2", "1549412699.000100"}} {
+		ref, ok := bot.threadIssue(thread.channel, thread.ts)
+		c.Assert(ok, Equals, true)
+		c.Assert(ref, Equals, to)
+	}
+	ref, ok := bot.threadIssue("C1", "1549412700.000300")
+	c.Assert(ok, Equals, true)
+	c.Assert(ref, Equals, other)
+}
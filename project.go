@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+)
+
+// projectFieldValue is the value to set a project field to from the settings. Single select options and
+// iterations are chosen by name, and an iteration can also be @current or @next.
+func projectFieldValue(field ProjectField, value string, now time.Time) (ProjectFieldValue, error) {
+	switch field.DataType {
+	case "TEXT":
+		return ProjectFieldValue{Text: &value}, nil
+	case "NUMBER":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return ProjectFieldValue{}, trace.BadParameter("%v isn't a number", value)
+		}
+		return ProjectFieldValue{Number: &number}, nil
+	case "DATE":
+		if value == "@today" {
+			value = now.Format("2006-01-02")
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return ProjectFieldValue{}, trace.BadParameter("%v isn't a date like 2006-01-02", value)
+		}
+		return ProjectFieldValue{Date: &value}, nil
+	case "SINGLE_SELECT":
+		var names []string
+		for _, option := range field.Options {
+			if strings.EqualFold(option.Name, value) {
+				id := option.ID
+				return ProjectFieldValue{SingleSelectOptionID: &id}, nil
+			}
+			names = append(names, option.Name)
+		}
+		return ProjectFieldValue{}, trace.BadParameter("%v isn't one of %v", value, strings.Join(names, ", "))
+	case "ITERATION":
+		iterations := append([]ProjectIteration(nil), field.Iterations...)
+		sort.Slice(iterations, func(i, j int) bool { return iterations[i].StartDate < iterations[j].StartDate })
+		today := now.Format("2006-01-02")
+		for i, iteration := range iterations {
+			start, err := time.Parse("2006-01-02", iteration.StartDate)
+			if err != nil {
+				continue
+			}
+			end := start.AddDate(0, 0, iteration.Duration).Format("2006-01-02")
+			current := iteration.StartDate <= today && today < end
+			switch {
+			case strings.EqualFold(iteration.Title, value),
+				value == "@current" && current,
+				value == "@next" && current && i+1 < len(iterations):
+				if value == "@next" {
+					iteration = iterations[i+1]
+				}
+				id := iteration.ID
+				return ProjectFieldValue{IterationID: &id}, nil
+			}
+		}
+		return ProjectFieldValue{}, trace.NotFound("there's no %v iteration", value)
+	}
+	return ProjectFieldValue{}, trace.BadParameter("%v fields can't be set", strings.ToLower(field.DataType))
+}
+
+// addToProject adds a new issue to the project for its channel or repo, if there is one, and sets the
+// project's field values on it. The issue is already filed, so problems are only reported to the user.
+func (s *SlackBot) addToProject(user, channel string, issue *Issue) {
+	ref := issue.Ref()
+	settings := s.settings.project(channel, ref.Owner+"/"+ref.Repo)
+	if settings == nil {
+		return
+	}
+	if !s.Begin() {
+		return
+	}
+	defer s.Done()
+	client := s.getGBotForUser(context.Background(), user)
+	if client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*TimeoutSeconds)
+	defer cancel()
+	report := func(problems []string) {
+		s.notify(user, channel, fmt.Sprintf("Filed %v, but %v", ref, strings.Join(problems, "; ")))
+	}
+	project, err := client.Project(ctx, settings.Owner, settings.Number)
+	if err != nil {
+		log.Infof("project error: %v", trace.DebugReport(err))
+		report([]string{fmt.Sprintf("couldn't find project %v of %v: %v", settings.Number, settings.Owner, trace.UserMessage(err))})
+		return
+	}
+	itemID, err := client.AddProjectItem(ctx, project.ID, ref)
+	if err != nil {
+		log.Infof("project item error: %v", trace.DebugReport(err))
+		report([]string{fmt.Sprintf("couldn't add it to %v: %v", project.Title, trace.UserMessage(err))})
+		return
+	}
+	s.audit(user, "add to project", ref, fmt.Sprintf("%v/%v", settings.Owner, settings.Number))
+
+	var names []string
+	for name := range settings.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []string
+	for _, name := range names {
+		value := settings.Fields[name]
+		var field *ProjectField
+		for i := range project.Fields {
+			if strings.EqualFold(project.Fields[i].Name, name) {
+				field = &project.Fields[i]
+			}
+		}
+		if field == nil {
+			problems = append(problems, fmt.Sprintf("%v doesn't have a %v field", project.Title, name))
+			continue
+		}
+		fieldValue, err := projectFieldValue(*field, value, time.Now())
+		if err == nil {
+			err = client.SetProjectField(ctx, project.ID, itemID, field.ID, fieldValue)
+		}
+		if err != nil {
+			log.Infof("project field error: %v", trace.DebugReport(err))
+			problems = append(problems, fmt.Sprintf("couldn't set %v to %v: %v", name, value, trace.UserMessage(err)))
+		}
+	}
+	if len(problems) != 0 {
+		report(problems)
+	}
+}
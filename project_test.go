@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ProjectSuite struct{}
+
+var _ = Suite(&ProjectSuite{})
+
+func (s *ProjectSuite) TestProjectSettings(c *C) {
+	var loaded settings
+	contents := `{"channels": {"C1": {"project": {"owner": "gravitational", "number": 7}}},
+		"repos": {"gravitational/teleport": {"project": {"owner": "gravitational", "number": 3, "fields": {"Status": "Todo"}}}}}`
+	c.Assert(json.Unmarshal([]byte(contents), &loaded), IsNil)
+	c.Assert(loaded.project("C1", "gravitational/teleport").Number, Equals, 7)
+	c.Assert(loaded.project("C2", "Gravitational/Teleport").Fields, DeepEquals, map[string]string{"Status": "Todo"})
+	c.Assert(loaded.project("C2", "gravitational/other"), IsNil)
+}
+
+func (s *ProjectSuite) TestProjectFieldValue(c *C) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	status := ProjectField{DataType: "SINGLE_SELECT", Options: []ProjectOption{{ID: "o1", Name: "Todo"}, {ID: "o2", Name: "In Progress"}}}
+	iteration := ProjectField{DataType: "ITERATION", Iterations: []ProjectIteration{
+		{ID: "i2", Title: "Sprint 2", StartDate: "2026-10-26", Duration: 14},
+		{ID: "i1", Title: "Sprint 1", StartDate: "2026-10-12", Duration: 14},
+	}}
+	tests := []struct {
+		field ProjectField
+		value string
+		want  ProjectFieldValue
+		err   bool
+	}{
+		{field: status, value: "in progress", want: ProjectFieldValue{SingleSelectOptionID: stringPointer("o2")}},
+		{field: status, value: "Done", err: true},
+		{field: iteration, value: "@current", want: ProjectFieldValue{IterationID: stringPointer("i1")}},
+		{field: iteration, value: "@next", want: ProjectFieldValue{IterationID: stringPointer("i2")}},
+		{field: iteration, value: "sprint 2", want: ProjectFieldValue{IterationID: stringPointer("i2")}},
+		{field: ProjectField{DataType: "DATE"}, value: "@today", want: ProjectFieldValue{Date: stringPointer("2026-10-19")}},
+		{field: ProjectField{DataType: "DATE"}, value: "tomorrow", err: true},
+		{field: ProjectField{DataType: "NUMBER"}, value: "three", err: true},
+		{field: ProjectField{DataType: "TITLE"}, value: "title", err: true},
+	}
+	for _, test := range tests {
+		value, err := projectFieldValue(test.field, test.value, now)
+		comment := Commentf("%v %q", test.field.DataType, test.value)
+		if test.err {
+			c.Assert(err, NotNil, comment)
+			continue
+		}
+		c.Assert(err, IsNil, comment)
+		c.Assert(value, DeepEquals, test.want, comment)
+	}
+}
+
+func stringPointer(s string) *string {
+	return &s
+}
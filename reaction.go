@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+const (
+	reactionStoreFile = "./reactions"
+	// maxTitleLength is how much of a message's first line is used as an issue title
+	maxTitleLength = 80
+)
+
+// fileReaction turns the message that was reacted to into an issue in the channel's repo,
+// then replies in the message's thread with the issue url.
+func (s *SlackBot) fileReaction(event *slack.ReactionAddedEvent) {
+	if event.Reaction != s.reaction || event.Item.Type != "message" {
+		return
+	}
+	if !s.Begin() {
+		return
+	}
+	defer s.Done()
+
+	channel, ts := event.Item.Channel, event.Item.Timestamp
+	key := channel + "/" + ts
+	api := s.sBot.Client()
+	ephemeral := func(text string) {
+		if _, err := api.PostEphemeral(channel, event.User, slack.MsgOptionText(text, false)); err != nil {
+			log.Errorf("couldn't reply to reaction: %v", trace.DebugReport(err))
+		}
+	}
+
+	if !s.IsAuthorized(event.User) {
+		log.Infof("ignoring :%v: from unauthorized user %v", event.Reaction, event.User)
+		return
+	}
+	release, ok := s.claimMessage(key)
+	if !ok {
+		return
+	}
+	defer release()
+
+	repo := s.settings.Channels[channel].Repo
+	if len(repo) == 0 {
+		ephemeral("This channel doesn't have a repo set up, so I can't file that")
+		return
+	}
+	client := s.getGBotForUser(context.Background(), event.User)
+	if client == nil {
+		ephemeral("You must register first, see `help` command")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*TimeoutSeconds)
+	defer cancel()
+	messages, _, _, err := api.GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+		ChannelID: channel,
+		Timestamp: ts,
+		Inclusive: true,
+		Limit:     1,
+	})
+	if err != nil || len(messages) == 0 {
+		log.Errorf("couldn't fetch reacted message %v: %v", key, err)
+		ephemeral("I couldn't read that message")
+		return
+	}
+	message := messages[0]
+	link, err := api.GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: channel, Ts: ts})
+	if err != nil {
+		log.Errorf("couldn't get permalink for %v: %v", key, trace.DebugReport(err))
+	}
+
+	body := s.toGitHub(message.Text) + "\n\n" + attribution("Filed", link, s.authorName(ctx, message, map[string]string{}))
+	issue, err := client.NewIssue(ctx, repo, messageTitle(s.directory.ToGitHub(message.Text)), body, IssueFields{})
+	if err != nil {
+		log.Infof("reaction issue error: %v", trace.DebugReport(err))
+		ephemeral("There was an error with the GitHub interface... Check 1) the channel's repo 2) the logs")
+		return
+	}
+	if err := s.reactions.Put(key, issue.Url); err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+
+	threadTS := message.ThreadTimestamp
+	if len(threadTS) == 0 {
+		threadTS = ts
+	}
+	s.recordIssue(event.User, channel, threadTS, issue)
+	_, _, err = api.PostMessage(channel, slack.MsgOptionText(issue.Url, false), slack.MsgOptionTS(threadTS))
+	if err != nil {
+		log.Errorf("couldn't reply in thread: %v", trace.DebugReport(err))
+	}
+}
+
+// claimMessage stops two reactions filing the same message. It reports whether the message still
+// needs filing, and if it does, no other reaction can file it until release is called.
+func (s *SlackBot) claimMessage(key string) (release func(), ok bool) {
+	if _, busy := s.reacting.LoadOrStore(key, true); busy {
+		return nil, false
+	}
+	release = func() { s.reacting.Delete(key) }
+	// Checked once it's claimed, so a reaction that finished filing it just before is seen
+	var url string
+	if filed, err := s.reactions.Get(key, &url); filed || err != nil {
+		log.Infof("message %v was already filed as %v", key, url)
+		release()
+		return nil, false
+	}
+	return release, true
+}
+
+// messageTitle makes an issue title out of the first line of a message.
+func messageTitle(text string) string {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = string(runes[:maxTitleLength-1]) + "…"
+	}
+	if len(title) == 0 {
+		title = "Issue filed from Slack"
+	}
+	return title
+}
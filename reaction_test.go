@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ReactionSuite struct {
+	dir string
+}
+
+var _ = Suite(&ReactionSuite{})
+
+func (s *ReactionSuite) SetUpTest(c *C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "issuebot")
+	c.Assert(err, IsNil)
+}
+
+func (s *ReactionSuite) TearDownTest(c *C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *ReactionSuite) TestClaimMessage(c *C) {
+	reactions, err := newFileStore(filepath.Join(s.dir, "reactions"))
+	c.Assert(err, IsNil)
+	bot := &SlackBot{reactions: reactions}
+
+	release, ok := bot.claimMessage("C1/1.0")
+	c.Assert(ok, Equals, true)
+	_, ok = bot.claimMessage("C1/1.0")
+	c.Assert(ok, Equals, false, Commentf("claimed twice"))
+	_, ok = bot.claimMessage("C1/2.0")
+	c.Assert(ok, Equals, true, Commentf("other messages can be claimed"))
+
+	// Filed while claimed, then released: the next reaction sees it's filed even though it's free
+	c.Assert(reactions.Put("C1/1.0", "https://github.com/gravitational/teleport/issues/1"), IsNil)
+	release()
+	_, ok = bot.claimMessage("C1/1.0")
+	c.Assert(ok, Equals, false, Commentf("claimed after filing"))
+	_, busy := bot.reacting.Load("C1/1.0")
+	c.Assert(busy, Equals, false, Commentf("a filed message stays unclaimed"))
+}
+
+func (s *ReactionSuite) TestMessageTitle(c *C) {
+	testTables := []struct {
+		name  string
+		text  string
+		title string
+	}{
+		{name: "First Line", text: "  tsh hangs\nwhen logging in", title: "tsh hangs"},
+		{name: "Empty", text: " \n", title: "Issue filed from Slack"},
+		{name: "Long", text: strings.Repeat("é", maxTitleLength+5), title: strings.Repeat("é", maxTitleLength-1) + "…"},
+	}
+	for i, tt := range testTables {
+		c.Assert(messageTitle(tt.text), Equals, tt.title, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+)
+
+const (
+	// defaultRulesFilePath is used in the flags list
+	defaultRulesFilePath = "./rules.json"
+	// testRulesCommand is the subcommand that shows which rules fire for a sample message
+	testRulesCommand = "test-rules"
+)
+
+var (
+	// slackChannelIDRegex matches a slack channel, group or DM ID
+	slackChannelIDRegex = regexp.MustCompile(`^[CGD][A-Z0-9]+$`)
+	// slackUserIDRegex matches a slack user ID
+	slackUserIDRegex = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+)
+
+// rule routes and labels new issues that match it. A rule fires when everything it matches on does;
+// a rule with nothing to match on always fires. Eg:
+//
+//	[{"name": "tsh", "channels": ["C0123ABCD"], "title": "(?i)\\btsh\\b",
+//	  "repo": "gravitational/teleport", "labels": ["tsh"], "assignees": ["ayjayt"]}]
+type rule struct {
+	Name string `json:"name"`
+	// Channels and Reporters are slack IDs, any of which match
+	Channels  []string `json:"channels"`
+	Reporters []string `json:"reporters"`
+	// Title and Body are regular expressions
+	Title string `json:"title"`
+	Body  string `json:"body"`
+
+	// Repo is the "owner/repo" the issue is filed in instead. The first rule with a repo wins.
+	Repo      string   `json:"repo"`
+	Labels    []string `json:"labels"`
+	Assignees []string `json:"assignees"`
+	// Template is put before the body
+	Template string `json:"template"`
+	// Stop skips the rules after this one when it fires
+	Stop bool `json:"stop"`
+
+	title *regexp.Regexp
+	body  *regexp.Regexp
+}
+
+// rules are evaluated in order for every new issue.
+type rules []*rule
+
+// ruleMessage is what rules match on.
+type ruleMessage struct {
+	Channel  string
+	Reporter string
+	Title    string
+	Body     string
+}
+
+// ruleOutcome is whether a rule fired, and why not if it didn't.
+type ruleOutcome struct {
+	Name  string
+	Fired bool
+	Why   string
+}
+
+// ruleResult is what the rules that fired do to a new issue.
+type ruleResult struct {
+	Outcomes []ruleOutcome
+	Repo     string
+	Fields   IssueFields
+	Template string
+}
+
+// loadRules reads the rules file. A missing file just means no rules. Rules that could never fire, eg
+// because a channel is a name rather than an ID, are rejected.
+func loadRules(file string) (rules, error) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Infof("No rules file at %v, continuing without one", file)
+			return nil, nil
+		}
+		return nil, trace.Wrap(err)
+	}
+	var loaded rules
+	if err := json.Unmarshal(contents, &loaded); err != nil {
+		return nil, trace.Wrap(err)
+	}
+	var stopper *rule // a rule before this one that always fires and stops the rules
+	for i, r := range loaded {
+		if len(r.Name) == 0 {
+			r.Name = fmt.Sprintf("#%v", i+1)
+		}
+		if stopper != nil {
+			return nil, trace.BadParameter("rule %v never fires, rule %v always fires before it and stops the rules", r.Name, stopper.Name)
+		}
+		for _, channel := range r.Channels {
+			if !slackChannelIDRegex.MatchString(channel) {
+				return nil, trace.BadParameter("rule %v's channel %q isn't a slack channel ID like C0123ABCD", r.Name, channel)
+			}
+		}
+		for _, reporter := range r.Reporters {
+			if !slackUserIDRegex.MatchString(reporter) {
+				return nil, trace.BadParameter("rule %v's reporter %q isn't a slack user ID like U0123ABCD", r.Name, reporter)
+			}
+		}
+		if r.title, err = compileRuleRegex(r.Title); err != nil {
+			return nil, trace.BadParameter("rule %v has a bad title regex: %v", r.Name, err)
+		}
+		if r.body, err = compileRuleRegex(r.Body); err != nil {
+			return nil, trace.BadParameter("rule %v has a bad body regex: %v", r.Name, err)
+		}
+		if len(r.Repo) != 0 {
+			if _, _, err := splitRepo(r.Repo); err != nil {
+				return nil, trace.BadParameter("rule %v's repo should be owner/repo", r.Name)
+			}
+		}
+		if r.Stop && r.always() {
+			stopper = r
+		}
+	}
+	return loaded, nil
+}
+
+// always reports whether a rule has nothing to match on, so it fires for every message.
+func (r *rule) always() bool {
+	return len(r.Channels) == 0 && len(r.Reporters) == 0 && r.title == nil && r.body == nil
+}
+
+// compileRuleRegex compiles a rule's regex, if it has one.
+func compileRuleRegex(expression string) (*regexp.Regexp, error) {
+	if len(expression) == 0 {
+		return nil, nil
+	}
+	return regexp.Compile(expression)
+}
+
+// matches reports whether a rule fires for message, and if not, why.
+func (r *rule) matches(message ruleMessage) (bool, string) {
+	if len(r.Channels) != 0 && !contains(r.Channels, message.Channel) {
+		return false, "channel isn't one of " + strings.Join(r.Channels, ", ")
+	}
+	if len(r.Reporters) != 0 && !contains(r.Reporters, message.Reporter) {
+		return false, "reporter isn't one of " + strings.Join(r.Reporters, ", ")
+	}
+	if r.title != nil && !r.title.MatchString(message.Title) {
+		return false, fmt.Sprintf("title doesn't match %v", r.Title)
+	}
+	if r.body != nil && !r.body.MatchString(message.Body) {
+		return false, fmt.Sprintf("body doesn't match %v", r.Body)
+	}
+	return true, ""
+}
+
+// contains reports whether list has value.
+func contains(list []string, value string) bool {
+	for _, element := range list {
+		if element == value {
+			return true
+		}
+	}
+	return false
+}
+
+// apply evaluates the rules for a message.
+func (rs rules) apply(message ruleMessage) ruleResult {
+	var result ruleResult
+	var templates []string
+	stopped := ""
+	for _, r := range rs {
+		if len(stopped) != 0 {
+			result.Outcomes = append(result.Outcomes, ruleOutcome{Name: r.Name, Why: "rule " + stopped + " stopped the rules"})
+			continue
+		}
+		fired, why := r.matches(message)
+		result.Outcomes = append(result.Outcomes, ruleOutcome{Name: r.Name, Fired: fired, Why: why})
+		if !fired {
+			continue
+		}
+		if len(result.Repo) == 0 {
+			result.Repo = r.Repo
+		}
+		for _, label := range r.Labels {
+			if !contains(result.Fields.Labels, label) {
+				result.Fields.Labels = append(result.Fields.Labels, label)
+			}
+		}
+		for _, assignee := range r.Assignees {
+			if !contains(result.Fields.Assignees, assignee) {
+				result.Fields.Assignees = append(result.Fields.Assignees, assignee)
+			}
+		}
+		if len(r.Template) != 0 {
+			templates = append(templates, r.Template)
+		}
+		if r.Stop {
+			stopped = r.Name
+		}
+	}
+	result.Template = strings.Join(templates, "\n\n")
+	return result
+}
+
+// body is a new issue's body with the rules' templates before it.
+func (result ruleResult) body(body string) string {
+	if len(result.Template) == 0 {
+		return body
+	}
+	if len(body) == 0 {
+		return result.Template
+	}
+	return result.Template + "\n\n" + body
+}
+
+// knownFields leaves out the labels and assignees the rules added that repo doesn't have, so a typo in
+// a rule doesn't stop issues being filed. They're logged so the rules can be fixed.
+func (result ruleResult) knownFields(ctx context.Context, client *GitHubIssueBot, repo string) IssueFields {
+	if len(result.Fields.Labels) == 0 && len(result.Fields.Assignees) == 0 {
+		return result.Fields
+	}
+	fields, dropped, err := client.KnownFields(ctx, repo, result.Fields)
+	if err != nil {
+		log.Errorf("couldn't check the rules' labels and assignees in %v: %v", repo, trace.DebugReport(err))
+		return result.Fields
+	}
+	if len(dropped) != 0 {
+		log.Errorf("%v doesn't have the rules' %v, left it out of the issue", repo, strings.Join(dropped, ", "))
+	}
+	return fields
+}
+
+// testRules is the test-rules subcommand. It shows which rules fire for a sample message, and what they'd do.
+// With a github client, it also warns about labels and assignees the repo doesn't have.
+func testRules(out io.Writer, file string, client *GitHubIssueBot, args []string) error {
+	flags := flag.NewFlagSet(testRulesCommand, flag.ContinueOnError)
+	flags.SetOutput(out)
+	channel := flags.String("channel", "", "Slack channel ID the message is in")
+	reporter := flags.String("user", "", "Slack user ID of the reporter")
+	asked := flags.String("repo", "", "The owner/repo the message asks for, if the rules don't pick one")
+	flags.Usage = func() {
+		fmt.Fprintf(out, "Usage: issuebot [--rules %v] [--github_token TOKEN] %v [--channel C0123ABCD] [--user U0123ABCD] [--repo owner/repo] \"title\" [\"body\"]\n",
+			defaultRulesFilePath, testRulesCommand)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return trace.Wrap(err)
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return trace.Wrap(ErrBadFlag)
+	}
+	loaded, err := loadRules(file)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	message := ruleMessage{Channel: *channel, Reporter: *reporter, Title: flags.Arg(0), Body: flags.Arg(1)}
+	result := loaded.apply(message)
+	for _, outcome := range result.Outcomes {
+		if outcome.Fired {
+			fmt.Fprintf(out, "✓ %v\n", outcome.Name)
+		} else {
+			fmt.Fprintf(out, "✗ %v: %v\n", outcome.Name, outcome.Why)
+		}
+	}
+	repo := result.Repo
+	if len(repo) == 0 {
+		repo = *asked
+	}
+	shown := repo
+	if len(shown) == 0 {
+		shown = "the one asked for"
+	}
+	fmt.Fprintf(out, "\nRepo: %v\nLabels: %v\nAssignees: %v\nBody:\n%v\n", shown,
+		strings.Join(result.Fields.Labels, ", "), strings.Join(result.Fields.Assignees, ", "), result.body(message.Body))
+
+	if len(result.Fields.Labels) == 0 && len(result.Fields.Assignees) == 0 {
+		return nil
+	}
+	if client == nil || len(repo) == 0 {
+		fmt.Fprintf(out, "\nPass --github_token and a --repo to check the labels and assignees exist\n")
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*TimeoutSeconds)
+	defer cancel()
+	_, dropped, err := client.KnownFields(ctx, repo, result.Fields)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	for _, unknown := range dropped {
+		fmt.Fprintf(out, "\nWarning: %v doesn't have the %v, it would be left out", repo, unknown)
+	}
+	if len(dropped) != 0 {
+		fmt.Fprintln(out)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type RulesSuite struct {
+	dir string
+}
+
+var _ = Suite(&RulesSuite{})
+
+const testRulesFile = `[
+	{"name": "tsh", "title": "(?i)\\btsh\\b", "repo": "gravitational/teleport", "labels": ["tsh", "bug"], "assignees": ["ayjayt"]},
+	{"name": "support", "channels": ["CSUPPORT"], "labels": ["bug", "support"], "template": "_Reported in #support_", "stop": true},
+	{"name": "security", "body": "(?i)cve", "repo": "gravitational/security", "labels": ["security"]}
+]`
+
+func (s *RulesSuite) SetUpTest(c *C) {
+	s.dir = c.MkDir()
+}
+
+func (s *RulesSuite) writeRules(c *C, contents string) string {
+	file := filepath.Join(s.dir, "rules.json")
+	c.Assert(ioutil.WriteFile(file, []byte(contents), 0600), IsNil)
+	return file
+}
+
+func (s *RulesSuite) TestLoadRules(c *C) {
+	loaded, err := loadRules(filepath.Join(s.dir, "missing.json"))
+	c.Assert(err, IsNil)
+	c.Assert(loaded, HasLen, 0)
+
+	_, err = loadRules(s.writeRules(c, `[{"title": "("}]`))
+	c.Assert(err, NotNil)
+	_, err = loadRules(s.writeRules(c, `[{"repo": "teleport"}]`))
+	c.Assert(err, NotNil)
+
+	// Rules that could never fire
+	_, err = loadRules(s.writeRules(c, `[{"name": "support", "channels": ["#support"], "labels": ["support"]}]`))
+	c.Assert(err, ErrorMatches, ".*channel \"#support\" isn't a slack channel ID.*")
+	_, err = loadRules(s.writeRules(c, `[{"name": "aj", "reporters": ["@aj"], "labels": ["support"]}]`))
+	c.Assert(err, ErrorMatches, ".*reporter \"@aj\" isn't a slack user ID.*")
+	_, err = loadRules(s.writeRules(c, `[{"name": "all", "labels": ["triage"], "stop": true}, {"name": "tsh", "title": "tsh"}]`))
+	c.Assert(err, ErrorMatches, "rule tsh never fires, rule all always fires before it and stops the rules")
+	_, err = loadRules(s.writeRules(c, `[{"name": "support", "channels": ["CSUPPORT"], "stop": true}, {"name": "all", "labels": ["triage"]}]`))
+	c.Assert(err, IsNil)
+}
+
+func (s *RulesSuite) TestApply(c *C) {
+	loaded, err := loadRules(s.writeRules(c, testRulesFile))
+	c.Assert(err, IsNil)
+
+	result := loaded.apply(ruleMessage{Channel: "CSUPPORT", Reporter: "U1", Title: "tsh crashes", Body: "CVE-2026-1"})
+	c.Assert(result.Outcomes, DeepEquals, []ruleOutcome{
+		{Name: "tsh", Fired: true},
+		{Name: "support", Fired: true},
+		{Name: "security", Why: "rule support stopped the rules"},
+	})
+	c.Assert(result.Repo, Equals, "gravitational/teleport")
+	c.Assert(result.Fields.Labels, DeepEquals, []string{"tsh", "bug", "support"})
+	c.Assert(result.Fields.Assignees, DeepEquals, []string{"ayjayt"})
+	c.Assert(result.body("It crashed"), Equals, "_Reported in #support_\n\nIt crashed")
+
+	result = loaded.apply(ruleMessage{Channel: "CDEV", Title: "web UI", Body: "see cve"})
+	c.Assert(result.Outcomes[0], DeepEquals, ruleOutcome{Name: "tsh", Why: `title doesn't match (?i)\btsh\b`})
+	c.Assert(result.Outcomes[1], DeepEquals, ruleOutcome{Name: "support", Why: "channel isn't one of CSUPPORT"})
+	c.Assert(result.Repo, Equals, "gravitational/security")
+	c.Assert(result.body("see cve"), Equals, "see cve")
+}
+
+func (s *RulesSuite) TestTestRules(c *C) {
+	file := s.writeRules(c, testRulesFile)
+	var out bytes.Buffer
+	c.Assert(testRules(&out, file, nil, []string{"--channel", "CDEV", "tsh crashes"}), IsNil)
+	c.Assert(out.String(), Equals, "✓ tsh\n"+
+		"✗ support: channel isn't one of CSUPPORT\n"+
+		"✗ security: body doesn't match (?i)cve\n"+
+		"\nRepo: gravitational/teleport\nLabels: tsh, bug\nAssignees: ayjayt\nBody:\n\n"+
+		"\nPass --github_token and a --repo to check the labels and assignees exist\n")
+
+	out.Reset()
+	c.Assert(testRules(&out, file, nil, nil), NotNil)
+}
+
+func (s *RulesSuite) TestUnknownLabels(c *C) {
+	client, done := testGitHubFunc(c, func(query string, variables map[string]interface{}) string {
+		switch {
+		case variables["label"] == "bug":
+			return `{"data": {"repository": {"label": {"id": "L1"}}}}`
+		case variables["label"] != nil:
+			return `{"data": {"repository": {"label": null}}}`
+		}
+		return `{"data": {"repository": {"assignableUsers": {"nodes": [{"id": "U1", "login": "ayjayt"}]}}}}`
+	})
+	defer done()
+	file := s.writeRules(c, testRulesFile)
+	loaded, err := loadRules(file)
+	c.Assert(err, IsNil)
+
+	result := loaded.apply(ruleMessage{Channel: "CDEV", Title: "tsh crashes"})
+	fields := result.knownFields(context.Background(), client, result.Repo)
+	c.Assert(fields, DeepEquals, IssueFields{Labels: []string{"bug"}, Assignees: []string{"ayjayt"}})
+
+	var out bytes.Buffer
+	c.Assert(testRules(&out, file, client, []string{"--channel", "CDEV", "tsh crashes"}), IsNil)
+	c.Assert(out.String(), Matches, "(?s).*\nWarning: gravitational/teleport doesn't have the label tsh, it would be left out\n$")
+
+	// The rules pick no repo for the support channel, so it's the one asked for
+	out.Reset()
+	c.Assert(testRules(&out, file, client, []string{"--channel", "CSUPPORT", "--repo", "gravitational/teleport", "web UI"}), IsNil)
+	c.Assert(out.String(), Matches, "(?s).*\nRepo: gravitational/teleport\n.*\nWarning: gravitational/teleport doesn't have the label support, it would be left out\n$")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+	"github.com/shomali11/proper"
+)
+
+const (
+	// SearchPageSize is how many search results are shown at a time
+	SearchPageSize = 5
+	// searchMoreActionID identifies the "More" button on search results
+	searchMoreActionID = "search_more"
+)
+
+var (
+	// searchRegex matches "search" followed by the rest of the message
+	searchRegex = regexp.MustCompile(`^\s*(?:<@(\S+)>)?\s*search\s+(.+?)\s*$`)
+	// searchScopeRegex finds qualifiers that already scope a search
+	searchScopeRegex = regexp.MustCompile(`(?:^|\s)(?:repo|org|user):\S`)
+)
+
+// searchPage is kept in the "More" button so the next page can be fetched.
+type searchPage struct {
+	Query string `json:"q"`
+	After string `json:"a"`
+}
+
+// searchParser matches "search <query>", where query is everything after "search".
+func (s *SlackBot) searchParser(text string) (*proper.Properties, bool) {
+	resultSlice := searchRegex.FindStringSubmatch(text)
+	if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
+		return nil, false
+	}
+	return proper.NewProperties(map[string]string{"query": resultSlice[2]}), true
+}
+
+// searchScope scopes a query to the channel's repo, or the default owner, unless it's already scoped.
+func (s *SlackBot) searchScope(channel, query string) string {
+	if searchScopeRegex.MatchString(query) {
+		return query
+	}
+	if repo := s.settings.Channels[channel].Repo; len(repo) != 0 {
+		return fmt.Sprintf("repo:%v %v", repo, query)
+	}
+	if owner := s.DefaultOwner(channel); len(owner) != 0 {
+		return fmt.Sprintf("org:%v %v", owner, query)
+	}
+	return query
+}
+
+// searchIssues is the callback for the "search" command.
+func (s *SlackBot) searchIssues(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	client, fellBack := s.gBotFor(r.Context(), event.User)
+	if !s.CheckClient(w, client) {
+		return
+	}
+	query := s.searchScope(event.Channel, r.StringParam("query", ""))
+	if fellBack {
+		query = publicSearch(query)
+	}
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	results, err := client.SearchIssues(subCtx, query, SearchPageSize, "")
+	if err != nil {
+		w.ReportError(fmt.Errorf("The search didn't work: %v", trace.UserMessage(err)))
+		log.Infof("search error: %v", trace.DebugReport(err))
+		return
+	}
+
+	text, blocks := s.searchBlocks(query, results)
+	options := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(blocks...),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+	if len(event.ThreadTimestamp) != 0 {
+		options = append(options, slack.MsgOptionTS(event.ThreadTimestamp))
+	}
+	if _, _, err = s.sBot.Client().PostMessage(event.Channel, options...); err != nil {
+		// Eg a slash command in a channel the bot isn't in
+		log.Infof("couldn't post search blocks: %v", err)
+		w.Reply(text)
+	}
+}
+
+// searchMore posts the next page of search results in place of the page whose "More" button was clicked.
+func (s *SlackBot) searchMore(ctx context.Context, payload *interaction, value string) {
+	if !s.Begin() {
+		return
+	}
+	defer s.Done()
+	var page searchPage
+	if err := json.Unmarshal([]byte(value), &page); err != nil {
+		log.Infof("bad search button value %q: %v", value, err)
+		return
+	}
+	client, fellBack := s.gBotFor(ctx, payload.User.ID)
+	if client == nil {
+		return
+	}
+	if fellBack {
+		page.Query = publicSearch(page.Query)
+	}
+	subCtx, cancel := context.WithTimeout(ctx, time.Second*TimeoutSeconds)
+	defer cancel()
+	response := slashResponse{ReplaceOriginal: true}
+	results, err := client.SearchIssues(subCtx, page.Query, SearchPageSize, page.After)
+	if err != nil {
+		log.Infof("search error: %v", trace.DebugReport(err))
+		response = slashResponse{Text: fmt.Sprintf("*Error:* _The search didn't work: %v_", trace.UserMessage(err))}
+	} else {
+		response.Text, response.Blocks = s.searchBlocks(page.Query, results)
+	}
+	if err := postResponseURL(payload.ResponseURL, response); err != nil {
+		log.Errorf("couldn't post to response_url: %v", trace.DebugReport(err))
+	}
+}
+
+// searchBlocks renders a page of search results as text and as a Block Kit message.
+func (s *SlackBot) searchBlocks(query string, results *SearchResults) (string, []slack.Block) {
+	lines := []string{fmt.Sprintf("%v results for `%v`", results.Total, query)}
+	for _, issue := range results.Issues {
+		line := fmt.Sprintf("<%v|%v> %v · _%v_", issue.Url, issue.Ref, escapeMrkdwn(issue.Title), strings.ToLower(issue.State))
+		for _, label := range issue.Labels {
+			line += fmt.Sprintf(" `%v`", escapeMrkdwn(label))
+		}
+		lines = append(lines, line)
+	}
+	text := strings.Join(lines, "\n")
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+	}
+	if results.HasMore {
+		value, err := json.Marshal(searchPage{Query: query, After: results.EndCursor})
+		if err != nil {
+			log.Errorf(trace.DebugReport(err))
+			return text, blocks
+		}
+		more := slack.NewButtonBlockElement(searchMoreActionID, string(value),
+			slack.NewTextBlockObject(slack.PlainTextType, "More", false, false))
+		blocks = append(blocks, slack.NewActionBlock("", more))
+	}
+	return text, blocks
+}
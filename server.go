@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+	"github.com/shomali11/commander"
+	"github.com/shomali11/proper"
+)
+
+const (
+	// SlackAckMilliseconds is how long a slash command runs before it's acknowledged and
+	// the rest of its replies go to the response_url. Slack gives up after 3 seconds.
+	SlackAckMilliseconds = 2500
+	// maxRequestBytes is the largest request body the HTTP server will read
+	maxRequestBytes = 1 << 20
+)
+
+var (
+	// ErrBadSignature is returned when a request wasn't signed with the slack signing secret
+	ErrBadSignature = errors.New("request signature didn't match the signing secret")
+)
+
+// newHTTPServer returns a server for slack's HTTP callbacks. ctx is used for the commands it runs,
+// since they outlive the HTTP requests that start them.
+func (s *SlackBot) newHTTPServer(ctx context.Context, addr, secret string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/slack/commands", verifySlack(secret, s.slashCommandHandler(ctx)))
+	mux.Handle("/slack/interactions", verifySlack(secret, s.interactionHandler(ctx)))
+	mux.Handle("/slack/events", verifySlack(secret, s.eventHandler(ctx)))
+	return &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+}
+
+// verifySlack only passes on requests signed with secret. slack.NewSecretsVerifier also rejects
+// timestamps more than five minutes old, so a captured request can't be replayed.
+func verifySlack(secret string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifier, err := slack.NewSecretsVerifier(r.Header, secret)
+		if err != nil {
+			log.Infof("rejected slack request: %v", err)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := verifier.Write(body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := verifier.Ensure(); err != nil {
+			log.Infof("rejected slack request: %v", err)
+			http.Error(w, ErrBadSignature.Error(), http.StatusUnauthorized)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// slashCommandHandler runs "/issue <command>" with the same handlers as RTM commands.
+func (s *SlackBot) slashCommandHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slash, err := slack.SlashCommandParse(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		event := &slack.MessageEvent{}
+		event.User = slash.UserID
+		event.Channel = slash.ChannelID
+		event.Text = strings.TrimSpace(slash.Text)
+
+		// With no command, open the new issue modal
+		if len(event.Text) == 0 {
+			draft := issueDraft{Repo: s.settings.Channels[slash.ChannelID].Repo}
+			if err := s.openIssueModal(ctx, slash.TriggerID, slash.UserID, slash.ChannelID, draft, nil); err != nil {
+				log.Infof("couldn't open modal: %v", trace.DebugReport(err))
+				writeJSON(w, slashResponse{Text: fmt.Sprintf("*Error:* _%v_", trace.UserMessage(err))})
+			}
+			return
+		}
+
+		req, handler := s.matchCommand(ctx, event)
+		if handler == nil {
+			writeJSON(w, slashResponse{Text: s.usage(slash.Command)})
+			return
+		}
+		responder := &slashResponder{responseURL: slash.ResponseURL}
+		done := make(chan struct{})
+		go func() {
+			handler(req, responder)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Millisecond * SlackAckMilliseconds):
+		}
+		replies := responder.flush()
+		if len(replies) == 0 {
+			replies = []string{"Working on it..."}
+		}
+		writeJSON(w, slashResponse{Text: strings.Join(replies, "\n")})
+	}
+}
+
+// interaction is the payload slack posts to the interactivity endpoint. It's a subset of the fields.
+type interaction struct {
+	Type        string `json:"type"`
+	CallbackID  string `json:"callback_id"`
+	TriggerID   string `json:"trigger_id"`
+	ResponseURL string `json:"response_url"`
+	User        struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message slack.Message `json:"message"`
+	View    viewState     `json:"view"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	// ActionID and Value are what's been typed into an external select, for a block_suggestion
+	ActionID string `json:"action_id"`
+	Value    string `json:"value"`
+}
+
+// interactionHandler receives modal submissions and other interactive components.
+func (s *SlackBot) interactionHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload interaction
+		if err := json.Unmarshal([]byte(r.PostFormValue("payload")), &payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch {
+		case payload.Type == "view_submission" && payload.View.CallbackID == newIssueCallbackID:
+			if validation := s.submitIssueModal(ctx, payload.User.ID, &payload.View); len(validation) != 0 {
+				writeJSON(w, map[string]interface{}{
+					"response_action": "errors",
+					"errors":          validation,
+				})
+				return
+			}
+		case payload.Type == "view_submission" && payload.View.CallbackID == templateCallbackID:
+			if validation := s.submitTemplateModal(ctx, payload.User.ID, &payload.View); len(validation) != 0 {
+				writeJSON(w, map[string]interface{}{
+					"response_action": "errors",
+					"errors":          validation,
+				})
+				return
+			}
+		case payload.Type == "block_suggestion" && payload.View.CallbackID == newIssueCallbackID && payload.ActionID == "repo":
+			writeJSON(w, s.suggestRepos(ctx, &payload))
+			return
+		case payload.Type == "message_action" && payload.CallbackID == issueShortcutCallbackID:
+			if err := s.openShortcutModal(ctx, &payload); err != nil {
+				log.Infof("couldn't open modal: %v", trace.DebugReport(err))
+				err = postResponseURL(payload.ResponseURL, slashResponse{Text: fmt.Sprintf("*Error:* _%v_", trace.UserMessage(err))})
+				if err != nil {
+					log.Errorf("couldn't post to response_url: %v", trace.DebugReport(err))
+				}
+			}
+		case payload.Type == "block_actions":
+			for _, action := range payload.Actions {
+				switch action.ActionID {
+				case searchMoreActionID:
+					go s.searchMore(ctx, &payload, action.Value)
+				case fileAnywayActionID, plusOneActionID, cancelPendingActionID, editPendingActionID:
+					go s.resolvePending(ctx, &payload, action.ActionID, action.Value)
+				case undoActionID:
+					go s.undoButton(ctx, &payload, action.Value)
+				case fillTemplateActionID:
+					go s.fillTemplateButton(ctx, &payload, action.Value)
+				}
+			}
+		default:
+			log.Infof("ignoring %v interaction %v", payload.Type, payload.CallbackID)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// eventCallback is the envelope of an Events API request.
+type eventCallback struct {
+	Type      string          `json:"type"`
+	Challenge string          `json:"challenge"`
+	Event     json.RawMessage `json:"event"`
+}
+
+// eventHandler receives the Events API events that RTM doesn't have, eg link_shared.
+func (s *SlackBot) eventHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var callback eventCallback
+		if err := json.NewDecoder(r.Body).Decode(&callback); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if callback.Type == "url_verification" {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(callback.Challenge))
+			return
+		}
+		var event struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(callback.Event, &event); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Slack wants an answer within 3 seconds, so events are handled after responding
+		switch event.Type {
+		case "link_shared":
+			linkShared := &linkSharedEvent{}
+			if err := json.Unmarshal(callback.Event, linkShared); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			go s.unfurlLinks(ctx, linkShared)
+		default:
+			log.Infof("ignoring %v event", event.Type)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// matchCommand finds the registered command that matches event.Text.
+func (s *SlackBot) matchCommand(ctx context.Context, event *slack.MessageEvent) (request, func(request, responder)) {
+	for _, c := range s.commands {
+		parse := c.definition.CustomParser
+		if parse == nil {
+			parse = commander.NewCommand(c.usage).Match
+		}
+		if properties, ok := parse(event.Text); ok {
+			return &httpRequest{ctx: ctx, event: event, properties: properties}, c.handler
+		}
+	}
+	return nil, nil
+}
+
+// usage lists the registered commands as a slash command would type them.
+func (s *SlackBot) usage(slashCommand string) string {
+	var lines []string
+	for _, c := range s.commands {
+		example := c.definition.Example
+		if len(example) == 0 {
+			example = c.usage
+		}
+		lines = append(lines, fmt.Sprintf("`%v %v` %v", slashCommand, example, c.definition.Description))
+	}
+	return "I didn't understand that. Try one of:\n" + strings.Join(lines, "\n")
+}
+
+// httpRequest is a request for a command that came over HTTP instead of RTM.
+type httpRequest struct {
+	ctx        context.Context
+	event      *slack.MessageEvent
+	properties *proper.Properties
+}
+
+// Context is the context the command runs in.
+func (r *httpRequest) Context() context.Context {
+	return r.ctx
+}
+
+// Event is a message event made up from the HTTP request.
+func (r *httpRequest) Event() *slack.MessageEvent {
+	return r.event
+}
+
+// StringParam returns a parameter the command was given.
+func (r *httpRequest) StringParam(key string, defaultValue string) string {
+	return r.properties.StringParam(key, defaultValue)
+}
+
+// slashResponse is the JSON that slack expects in reply to a slash command or on its response_url.
+type slashResponse struct {
+	Text            string        `json:"text"`
+	Blocks          []slack.Block `json:"blocks,omitempty"`
+	ReplaceOriginal bool          `json:"replace_original,omitempty"`
+}
+
+// slashResponder collects replies until it's flushed into the HTTP response. After that,
+// replies are posted to the response_url.
+type slashResponder struct {
+	mu          sync.Mutex
+	responseURL string
+	replies     []string
+	flushed     bool
+}
+
+// Reply sends text to the user who ran the command.
+func (sr *slashResponder) Reply(text string) {
+	sr.send(text)
+}
+
+// ReportError sends err to the user who ran the command.
+func (sr *slashResponder) ReportError(err error) {
+	sr.send(fmt.Sprintf("*Error:* _%v_", err))
+}
+
+func (sr *slashResponder) send(text string) {
+	sr.mu.Lock()
+	if !sr.flushed {
+		sr.replies = append(sr.replies, text)
+		sr.mu.Unlock()
+		return
+	}
+	sr.mu.Unlock()
+	if err := postResponseURL(sr.responseURL, slashResponse{Text: text}); err != nil {
+		log.Errorf("couldn't post to response_url: %v", trace.DebugReport(err))
+	}
+}
+
+// flush returns the replies so far, and sends any more to the response_url.
+func (sr *slashResponder) flush() []string {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.flushed = true
+	return sr.replies
+}
+
+// postResponseURL posts a message to a slack response_url.
+func postResponseURL(url string, message interface{}) error {
+	contents, err := json.Marshal(message)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(contents))
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return trace.Errorf("response_url returned %v", resp.Status)
+	}
+	return nil
+}
+
+// writeJSON writes value to an HTTP response as JSON.
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Errorf("couldn't write response: %v", err)
+	}
+}
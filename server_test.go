@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ServerSuite struct{}
+
+var _ = Suite(&ServerSuite{})
+
+// signedRequest makes a request the way slack signs them.
+func signedRequest(secret, body string, when time.Time) *http.Request {
+	timestamp := strconv.FormatInt(when.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%v:%v", timestamp, body)
+	req := httptest.NewRequest("POST", "/slack/commands", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func (s *ServerSuite) TestVerifySlack(c *C) {
+	const secret = "fake-signing-secret"
+	const body = "text=new"
+	handler := verifySlack(secret, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	testTables := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{name: "Good", req: signedRequest(secret, body, time.Now()), status: http.StatusTeapot},
+		{name: "Wrong Secret", req: signedRequest("wrong-secret", body, time.Now()), status: http.StatusUnauthorized},
+		{name: "Replayed", req: signedRequest(secret, body, time.Now().Add(-10*time.Minute)), status: http.StatusUnauthorized},
+		{name: "Unsigned", req: httptest.NewRequest("POST", "/slack/commands", strings.NewReader(body)), status: http.StatusUnauthorized},
+	}
+	for i, tt := range testTables {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, tt.req)
+		c.Assert(recorder.Code, Equals, tt.status, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
+
+func (s *ServerSuite) TestRepoSuggestions(c *C) {
+	bot := &SlackBot{org: "gravitational"}
+	payload := `{"type": "block_suggestion", "action_id": "repo", "value": "teleport", "user": {"id": "U1"},
+		"view": {"callback_id": "new_issue", "private_metadata": "{\"channel\":\"C0123ABCD\"}"}}`
+	req := httptest.NewRequest("POST", "/slack/interactions", strings.NewReader(url.Values{"payload": {payload}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	bot.interactionHandler(context.Background()).ServeHTTP(recorder, req)
+	c.Assert(recorder.Code, Equals, http.StatusOK)
+	// Unregistered, so only what was typed is offered, with the channel's owner
+	c.Assert(strings.TrimSpace(recorder.Body.String()), Equals,
+		`{"options":[{"text":{"type":"plain_text","text":"gravitational/teleport"},"value":"gravitational/teleport"}]}`)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+)
+
+const (
+	// defaultSettingsFilePath is used in the flags list
+	defaultSettingsFilePath = "./settings.json"
+)
+
+// settings is the operator's per-channel and per-repo configuration, loaded from a JSON file. Eg:
+//
+//	{"channels": {"C0123ABCD": {"repo": "gravitational/teleport"}},
+//	 "repos": {"gravitational/teleport": {"confirm": true}}}
+type settings struct {
+	// Channels maps a slack channel ID to its settings
+	Channels map[string]channelSettings `json:"channels"`
+	// Repos maps an "owner/repo" to its settings
+	Repos map[string]repoSettings `json:"repos"`
+}
+
+// channelSettings configures the bot's behavior in one slack channel.
+type channelSettings struct {
+	// Repo is the "owner/repo" that issues from this channel are filed in
+	Repo string `json:"repo"`
+	// Expand turns on replying to owner/repo#N references with a summary of the issue
+	Expand bool `json:"expand"`
+	// Owner is used for repo#N references, instead of --org
+	Owner string `json:"owner"`
+	// Project is where issues filed from this channel are added, instead of their repo's project
+	Project *projectSettings `json:"project"`
+}
+
+// repoSettings configures how the bot files issues in one repo.
+type repoSettings struct {
+	// Confirm makes "new" show a preview that has to be submitted before the issue is filed. new-batch
+	// won't file in the repo.
+	Confirm bool `json:"confirm"`
+	// Project is where new issues in this repo are added
+	Project *projectSettings `json:"project"`
+}
+
+// projectSettings adds new issues to a Projects (v2) board.
+type projectSettings struct {
+	// Owner is the org or user the project belongs to, and Number is its number in their projects
+	Owner  string `json:"owner"`
+	Number int    `json:"number"`
+	// Fields are the values set on new items, by field name, eg {"Status": "Todo", "Iteration": "@current"}
+	Fields map[string]string `json:"fields"`
+}
+
+// project finds where an issue filed from channel in repo is added, if anywhere.
+func (s settings) project(channel, repo string) *projectSettings {
+	if project := s.Channels[channel].Project; project != nil {
+		return project
+	}
+	return s.repo(repo).Project
+}
+
+// repo finds a repo's settings. Repo names aren't case sensitive.
+func (s settings) repo(name string) repoSettings {
+	for repo, settings := range s.Repos {
+		if strings.EqualFold(repo, name) {
+			return settings
+		}
+	}
+	return repoSettings{}
+}
+
+// loadSettings reads the settings file. A missing file just means no settings.
+func (c *config) loadSettings() error {
+	contents, err := ioutil.ReadFile(c.settingsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Infof("No settings file at %v, continuing without one", c.settingsFile)
+			return nil
+		}
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(json.Unmarshal(contents, &c.settings))
+}
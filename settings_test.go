@@ -0,0 +1,19 @@
+package main
+
+import (
+	"encoding/json"
+
+	. "gopkg.in/check.v1"
+)
+
+type SettingsSuite struct{}
+
+var _ = Suite(&SettingsSuite{})
+
+func (s *SettingsSuite) TestRepoSettings(c *C) {
+	var loaded settings
+	contents := `{"channels": {"C0123ABCD": {"repo": "gravitational/teleport"}}, "repos": {"Gravitational/Teleport": {"confirm": true}}}`
+	c.Assert(json.Unmarshal([]byte(contents), &loaded), IsNil)
+	c.Assert(loaded.repo(loaded.Channels["C0123ABCD"].Repo).Confirm, Equals, true)
+	c.Assert(loaded.repo("gravitational/other").Confirm, Equals, false)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+const (
+	// maxExcerptLength is how much of an issue body or comment is shown in slack
+	maxExcerptLength = 500
+)
+
+var (
+	// ErrBadIssueRef is reported when a user doesn't give an issue as owner/repo#N
+	ErrBadIssueRef = errors.New("Issues look like owner/repo#N, or repo#N for repos in the default org")
+	// mrkdwnEscaper escapes the characters slack treats as control characters in mrkdwn
+	mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// showIssue is the callback for the "show" command, which renders an issue's details.
+func (s *SlackBot) showIssue(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	ref, ok := parseIssueRef(r.StringParam("ref", ""), s.DefaultOwner(event.Channel))
+	if !ok {
+		w.ReportError(ErrBadIssueRef)
+		return
+	}
+	client, fellBack := s.gBotFor(r.Context(), event.User)
+	if !s.CheckClient(w, client) {
+		return
+	}
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	details, err := getIssueFor(subCtx, client, fellBack, ref)
+	if trace.Unwrap(err) == ErrPrivateRepo {
+		w.ReportError(ErrPrivateRepo)
+		return
+	}
+	if err != nil {
+		w.ReportError(fmt.Errorf("Couldn't find %v", ref))
+		log.Infof("show issue error: %v", trace.DebugReport(err))
+		return
+	}
+
+	fallback := fmt.Sprintf("%v %v (%v)", details.Url, escapeMrkdwn(details.Title), strings.ToLower(details.State))
+	options := []slack.MsgOption{
+		slack.MsgOptionText(fallback, false),
+		slack.MsgOptionBlocks(s.issueBlocks(details)...),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+	if len(event.ThreadTimestamp) != 0 {
+		options = append(options, slack.MsgOptionTS(event.ThreadTimestamp))
+	}
+	if _, _, err = s.sBot.Client().PostMessage(event.Channel, options...); err != nil {
+		// Eg a slash command in a channel the bot isn't in
+		log.Infof("couldn't post issue blocks: %v", err)
+		w.Reply(fallback)
+	}
+}
+
+// issueBlocks renders an issue's details as a Block Kit message. Text from github is escaped, so eg
+// a <tag> in a body is shown rather than taken for a link or mention.
+func (s *SlackBot) issueBlocks(details *IssueDetails) []slack.Block {
+	mrkdwn := func(text string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.MarkdownType, text, false, false)
+	}
+	var assignees []string
+	for _, login := range details.Assignees {
+		assignees = append(assignees, s.directory.ToSlack("@"+login))
+	}
+	milestone := escapeMrkdwn(details.Milestone)
+	if len(milestone) == 0 {
+		milestone = "_none_"
+	}
+	var labels []string
+	for _, label := range details.Labels {
+		labels = append(labels, escapeMrkdwn(label))
+	}
+
+	blocks := []slack.Block{
+		slack.NewSectionBlock(mrkdwn(fmt.Sprintf("*<%v|%v>* %v", details.Url, details.Ref, escapeMrkdwn(details.Title))), nil, nil),
+		slack.NewSectionBlock(nil, []*slack.TextBlockObject{
+			mrkdwn("*State*\n" + strings.Title(strings.ToLower(details.State))),
+			mrkdwn("*Author*\n" + s.directory.ToSlack("@"+details.Author)),
+			mrkdwn("*Labels*\n" + listOrNone(labels)),
+			mrkdwn("*Assignees*\n" + listOrNone(assignees)),
+			mrkdwn("*Milestone*\n" + milestone),
+			mrkdwn(fmt.Sprintf("*Comments*\n%v", details.Comments)),
+		}, nil),
+	}
+	if body := excerpt(details.Body); len(body) != 0 {
+		blocks = append(blocks, slack.NewSectionBlock(mrkdwn(escapeMrkdwn(body)), nil, nil))
+	}
+	if len(details.LatestComments) != 0 {
+		blocks = append(blocks, slack.NewDividerBlock())
+	}
+	for _, comment := range details.LatestComments {
+		blocks = append(blocks,
+			slack.NewContextBlock("", mrkdwn(fmt.Sprintf("%v <%v|commented> %v",
+				s.directory.ToSlack("@"+comment.Author), comment.Url, comment.CreatedAt.UTC().Format("2006-01-02 15:04 MST")))),
+			slack.NewSectionBlock(mrkdwn(escapeMrkdwn(excerpt(comment.Body))), nil, nil))
+	}
+	return blocks
+}
+
+// excerpt shortens text to maxExcerptLength.
+func excerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if runes := []rune(text); len(runes) > maxExcerptLength {
+		text = string(runes[:maxExcerptLength-1]) + "…"
+	}
+	return text
+}
+
+// escapeMrkdwn escapes text from github for slack mrkdwn.
+func escapeMrkdwn(text string) string {
+	return mrkdwnEscaper.Replace(text)
+}
+
+// listOrNone joins a list for display.
+func listOrNone(list []string) string {
+	if len(list) == 0 {
+		return "_none_"
+	}
+	return strings.Join(list, ", ")
+}
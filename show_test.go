@@ -0,0 +1,38 @@
+package main
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type ShowSuite struct{}
+
+var _ = Suite(&ShowSuite{})
+
+func (s *ShowSuite) TestEscapeMrkdwn(c *C) {
+	testTables := []struct {
+		name    string
+		text    string
+		escaped string
+	}{
+		{name: "Plain", text: "tsh login hangs", escaped: "tsh login hangs"},
+		{name: "Tag", text: "Fix <details> rendering", escaped: "Fix &lt;details&gt; rendering"},
+		{name: "Fake Link", text: "<https://evil.example|click me> & <!channel>", escaped: "&lt;https://evil.example|click me&gt; &amp; &lt;!channel&gt;"},
+		{name: "Already Escaped", text: "a &lt; b", escaped: "a &amp;lt; b"},
+	}
+	for i, tt := range testTables {
+		c.Assert(escapeMrkdwn(tt.text), Equals, tt.escaped, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
+
+func (s *ShowSuite) TestSearchBlocksEscaped(c *C) {
+	bot := &SlackBot{}
+	results := &SearchResults{Total: 1, Issues: []IssueDetails{{
+		Ref:    issueRef{Owner: "gravitational", Repo: "teleport", Number: 1},
+		Title:  "<!here> breaks",
+		Url:    "https://github.com/gravitational/teleport/issues/1",
+		State:  "OPEN",
+		Labels: []string{"a&b"},
+	}}}
+	text, _ := bot.searchBlocks("breaks", results)
+	c.Assert(text, Equals, "1 results for `breaks`\n<https://github.com/gravitational/teleport/issues/1|gravitational/teleport#1> &lt;!here&gt; breaks · _open_ `a&amp;b`")
+}
@@ -7,12 +7,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ayjayt/slacker"
 	"github.com/gravitational/trace"
 	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
 	"github.com/shomali11/proper"
 )
 
@@ -45,17 +47,59 @@ func init() {
 	escapeRegex = regexp.MustCompile(`\\(.)`)
 }
 
+// request is the part of slacker.Request that commands use. Commands take a request
+// instead of a slacker.Request so they can be run from places other than RTM, eg slash commands.
+type request interface {
+	Context() context.Context
+	Event() *slack.MessageEvent
+	StringParam(key string, defaultValue string) string
+}
+
+// responder is the part of slacker.ResponseWriter that commands use.
+type responder interface {
+	Reply(text string)
+	ReportError(err error)
+}
+
+// command is a registered command. It's kept so commands can be dispatched without RTM.
+type command struct {
+	usage      string
+	definition *slacker.CommandDefinition
+	handler    func(request, responder)
+}
+
 // SlackBot is a wrapper for the underlying slackbot to include some important variabales
 type SlackBot struct {
 	sBot                *slacker.Slacker
+	token               string
+	fallback            *GitHubIssueBot   // from --github_token, for reads when a user hasn't registered
 	gBots               sync.Map          // TODO: By user, maybe a slack user type
 	userTokens          map[string]string // TODO: Protect this against concurrent access
 	userTokensFileLock  sync.Mutex
 	userTokensFileQueue int
 	// TODO: default gBot based on token
-	wg      *sync.WaitGroup
-	running bool
-	botID   string
+	directory   *userDirectory
+	authedUsers []string
+	admins      []string
+	settings    settings
+	org         string
+	expanded    expandedRefs
+	pending     pendingIssues // issues waiting on possible duplicates
+	reaction    string
+	reactions   *fileStore    // message -> issue url, so a message is only filed once
+	reacting    sync.Map      // messages being filed right now
+	threads     *fileStore    // thread -> the issue it's about
+	creators    *fileStore    // issue -> the slack user who filed it
+	drafts      *fileStore    // slack user -> the issue they're drafting
+	guided      conversations // guided new issues, by slack user
+	rules       rules         // route, label and assign issues from "new"
+	recent      recentIssues
+	undoWindow  time.Duration
+	auditLog    auditLog
+	commands    []command
+	wg          *sync.WaitGroup
+	running     bool
+	botID       string
 }
 
 // readStore finds the file storing tokens and demarshals it into gBots sync.Map
@@ -95,28 +139,23 @@ func (s *SlackBot) writeStore() {
 	}()
 }
 
-// newIssueParser takes a whole command and matches and creates three params. It's a custom parser for one command. TODO: add snippets and default detection
+// newIssueParser takes a whole command and matches and creates three params. It's a custom parser for one command. TODO: default detection
 func (s *SlackBot) newIssueParser(text string) (*proper.Properties, bool) {
 	log.Infof("in newIssueParser for %v with %v", s.botID, text)
 	resultSlice := issueRegex.FindStringSubmatch(text) // TODO remove all botnames that aren't quoted before this
 
-	var wordOffset = 0
-	if (len(resultSlice) < 2) || (len(resultSlice[1]) == 0) || (len(resultSlice[2]) == 0) || (len(resultSlice[3]) == 0) {
+	// The mention is optional (DMs and slash commands don't have one), but it has to be us
+	if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
 		return nil, false
 	}
-	if len(resultSlice) == 5 {
-		if (len(resultSlice[4]) == 0) || (resultSlice[1] != s.botID) {
-			return nil, false
-		} else {
-			wordOffset = 1
-		}
+	if (len(resultSlice[2]) == 0) || (len(resultSlice[3]) == 0) || (len(resultSlice[4]) == 0) {
+		return nil, false
 	}
-	getMatch := func(matched string) string { return matched }
-	deEscape := func(escaped string) string { return escapeRegex.ReplaceAllStringFunc(escaped, getMatch) }
+	deEscape := func(escaped string) string { return escapeRegex.ReplaceAllString(escaped, "$1") }
 	parameters := make(map[string]string)
-	parameters["repo"] = deEscape(resultSlice[1+wordOffset])
-	parameters["title"] = deEscape(resultSlice[2+wordOffset])
-	parameters["body"] = deEscape(resultSlice[3+wordOffset])
+	parameters["repo"] = deEscape(resultSlice[2])
+	parameters["title"] = deEscape(resultSlice[3])
+	parameters["body"] = deEscape(resultSlice[4])
 	return proper.NewProperties(parameters), true
 
 }
@@ -126,15 +165,20 @@ func (s *SlackBot) newIssueParser(text string) (*proper.Properties, bool) {
 *************/
 
 // GetGBot can find the relevant github client for a particular slack user. or initialize it
-func (s *SlackBot) GetGBot(r slacker.Request) *GitHubIssueBot {
-	log.Infof("Getting bot for : %v", r.Event().User)
-	ret, ok := s.gBots.Load(r.Event().User)
+func (s *SlackBot) GetGBot(r request) *GitHubIssueBot {
+	return s.getGBotForUser(r.Context(), r.Event().User)
+}
+
+// getGBotForUser is GetGBot for when there's a slack user but no slacker.Request, eg reactions.
+func (s *SlackBot) getGBotForUser(ctx context.Context, user string) *GitHubIssueBot {
+	log.Infof("Getting bot for : %v", user)
+	ret, ok := s.gBots.Load(user)
 	if !ok {
-		diskCheck, ok := s.userTokens[r.Event().User] // TODO: write/read concurrency issues. Only one at a time, or mutexes, or a queue.
+		diskCheck, ok := s.userTokens[user] // TODO: write/read concurrency issues. Only one at a time, or mutexes, or a queue.
 		if ok {
-			ret = NewGitHubIssueBot(r.Context(), diskCheck)
+			ret = NewGitHubIssueBot(ctx, diskCheck)
 			// TODO: this needs testing
-			s.gBots.Store(r.Event().User, ret)
+			s.gBots.Store(user, ret)
 		} else {
 			return nil
 		}
@@ -143,7 +187,7 @@ func (s *SlackBot) GetGBot(r slacker.Request) *GitHubIssueBot {
 }
 
 // SetGBot will create a new user-github association
-func (s *SlackBot) SetGBot(r slacker.Request, gBot *GitHubIssueBot) bool {
+func (s *SlackBot) SetGBot(r request, gBot *GitHubIssueBot) bool {
 	// TODO: maybe testing should be in here -- definitely
 	_, ok := s.gBots.Load(r.Event().User)
 	if ok {
@@ -156,7 +200,7 @@ func (s *SlackBot) SetGBot(r slacker.Request, gBot *GitHubIssueBot) bool {
 }
 
 // DeleteGBot will create a new user-github association
-func (s *SlackBot) DeleteGBot(r slacker.Request) {
+func (s *SlackBot) DeleteGBot(r request) {
 	log.Infof("Deleting bot") // TODO: all log ettiquette
 	s.gBots.Delete(r.Event().User)
 	delete(s.userTokens, r.Event().User)
@@ -168,24 +212,40 @@ func (s *SlackBot) DeleteGBot(r slacker.Request) {
 *************/
 
 // createNewIssue is the callback containing logic for "new" command on Slack.
-func (s *SlackBot) createNewIssue(r slacker.Request, w slacker.ResponseWriter) {
+func (s *SlackBot) createNewIssue(r request, w responder) {
 	if s.CheckRun(w) {
 		defer s.Done()
 	} else {
 		return
 	}
 	repo := r.StringParam("repo", "")
-	title := r.StringParam("title", "")
-	body := r.StringParam("body", "")
+	title := s.directory.ToGitHub(r.StringParam("title", ""))
+	body := s.toGitHub(r.StringParam("body", ""))
+	if files := r.Event().Files; len(files) != 0 {
+		budget := MaxFileBytes - len(body)
+		body += "\n\n" + s.filesToMarkdown(files, &budget)
+	}
+	// The operator's rules can route the issue elsewhere, label and assign it, and add to its body
+	routed := s.rules.apply(ruleMessage{Channel: r.Event().Channel, Reporter: r.Event().User, Title: title, Body: body})
+	if len(routed.Repo) != 0 {
+		repo = routed.Repo
+	}
+	body = routed.body(body)
 
 	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
 	defer cancel()
+	body = withAttribution(body, s.commandAttribution(subCtx, r, "Filed"))
 
 	client := s.GetGBot(r)
 	if !s.CheckClient(w, client) { // TODO: This could be in auth
 		return
 	}
-	issue, err := client.NewIssue(subCtx, repo, title, body) // TODO: you'll panic if they delete while doing this
+	fields := routed.knownFields(subCtx, client, repo)
+	draft := pendingIssue{Repo: repo, Title: title, Body: body, Fields: fields}
+	if s.previewIssue(r, w, draft) || s.checkDuplicates(subCtx, r, w, client, draft) {
+		return
+	}
+	issue, err := client.NewIssue(subCtx, repo, title, body, fields) // TODO: you'll panic if they delete while doing this
 	if err != nil || subCtx.Err() != nil {
 		if err != nil {
 			w.ReportError(errors.New("There was an error with the GitHub interface... Check 1) the repo name 2) the logs"))
@@ -199,11 +259,29 @@ func (s *SlackBot) createNewIssue(r slacker.Request, w slacker.ResponseWriter) {
 		}
 		return
 	}
-	w.Reply(issue.Url)
-	return
+	event := r.Event()
+	s.recordIssue(event.User, event.Channel, event.ThreadTimestamp, issue)
+	s.postNewIssue(r, w, issue)
 }
 
-func (s *SlackBot) registerUser(r slacker.Request, w slacker.ResponseWriter) {
+// postNewIssue posts a new issue's url where it was asked for, with an Undo button.
+func (s *SlackBot) postNewIssue(r request, w responder, issue *Issue) {
+	event := r.Event()
+	options := []slack.MsgOption{
+		slack.MsgOptionText(issue.Url, false),
+		slack.MsgOptionBlocks(s.undoBlocks(issue.Url, issue.Ref())...),
+	}
+	if len(event.ThreadTimestamp) != 0 {
+		options = append(options, slack.MsgOptionTS(event.ThreadTimestamp))
+	}
+	if _, _, err := s.sBot.Client().PostMessage(event.Channel, options...); err != nil {
+		// Eg a slash command in a channel the bot isn't in, "undo" still works
+		log.Infof("couldn't post new issue: %v", err)
+		w.Reply(issue.Url)
+	}
+}
+
+func (s *SlackBot) registerUser(r request, w responder) {
 	// BUG(AJ) THIS WILL REPEAT IF YOU DO IT RIGHT AWAY OR SOMETHING EVNE IF THEY FIND YOU
 	if s.CheckRun(w) {
 		defer s.Done()
@@ -229,61 +307,288 @@ func (s *SlackBot) registerUser(r slacker.Request, w slacker.ResponseWriter) {
 		w.ReportError(errors.New("User already registered, please delete first."))
 		return
 	}
-	w.Reply(fmt.Sprintf("User successfully registered: %v, %v", name, login))
+	if err := s.directory.Set(r.Event().User, login, false); err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	w.Reply(s.directory.ToSlack(fmt.Sprintf("User successfully registered: %v, @%v", name, login)))
 	return
 }
 
-func (s *SlackBot) deleteUser(r slacker.Request, w slacker.ResponseWriter) {
+func (s *SlackBot) deleteUser(r request, w responder) {
 	if s.CheckRun(w) {
 		defer s.Done()
 	} else {
 		return
 	}
 	s.DeleteGBot(r)
+	if err := s.directory.Remove(r.Event().User, false); err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
 	w.Reply("If you had registered, you are no longer.")
 	return
 }
 
+// mapUser is the callback for the admin "map" command, which adds a manual directory entry.
+func (s *SlackBot) mapUser(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	if !s.CheckAdmin(r, w) {
+		return
+	}
+	match := slackMentionRegex.FindStringSubmatch(r.StringParam("user", ""))
+	login := strings.TrimPrefix(r.StringParam("login", ""), "@")
+	if match == nil || len(login) == 0 {
+		w.ReportError(ErrBadParams)
+		return
+	}
+	if err := s.directory.Set(match[1], login, true); err != nil {
+		w.ReportError(errors.New("Couldn't save the directory, check the logs"))
+		log.Errorf(trace.DebugReport(err))
+		return
+	}
+	w.Reply(fmt.Sprintf("<@%v> is now @%v on GitHub", match[1], login))
+}
+
+// unmapUser is the callback for the admin "unmap" command, which removes any directory entry.
+func (s *SlackBot) unmapUser(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	if !s.CheckAdmin(r, w) {
+		return
+	}
+	match := slackMentionRegex.FindStringSubmatch(r.StringParam("user", ""))
+	if match == nil {
+		w.ReportError(ErrBadParams)
+		return
+	}
+	if err := s.directory.Remove(match[1], true); err != nil {
+		w.ReportError(errors.New("Couldn't save the directory, check the logs"))
+		log.Errorf(trace.DebugReport(err))
+		return
+	}
+	w.Reply(fmt.Sprintf("<@%v> is no longer in the directory", match[1]))
+}
+
 /*************
 * The following are initializers
 *************/
 
+// Command registers a command with slacker, and with the bot so it can be dispatched from outside RTM.
+func (s *SlackBot) Command(usage string, definition *slacker.CommandDefinition, handler func(request, responder)) {
+	definition.Handler = func(r slacker.Request, w slacker.ResponseWriter) {
+		// Answers in a guided new issue can look like commands
+		if s.converse(r, w) {
+			return
+		}
+		handler(r, w)
+	}
+	s.commands = append(s.commands, command{usage: usage, definition: definition, handler: handler})
+	s.sBot.Command(usage, definition)
+}
+
 // newSlackBot BotLink and Slacker (bot) type, and calls Slacker.Listen
-func newSlackBot(token string, authedUsers []string) *SlackBot {
+func newSlackBot(cfg config) *SlackBot {
 
 	slackBot := &SlackBot{
-		sBot:    slacker.NewClient(token),
-		wg:      &sync.WaitGroup{},
-		running: false,
+		sBot:        slacker.NewClient(cfg.slackToken),
+		token:       cfg.slackToken,
+		authedUsers: cfg.authedUsers,
+		admins:      cfg.admins,
+		settings:    cfg.settings,
+		org:         cfg.org,
+		reaction:    cfg.reaction,
+		undoWindow:  cfg.undoWindow,
+		rules:       cfg.rules,
+		wg:          &sync.WaitGroup{},
+		running:     false,
 	}
 	err := slackBot.readStore()
 	if err != nil {
 		log.Errorf(trace.DebugReport(err))
 	}
+	slackBot.directory, err = newUserDirectory(userDirectoryFile)
+	if err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	if len(cfg.gitHubToken) != 0 {
+		slackBot.fallback = NewGitHubIssueBot(context.Background(), cfg.gitHubToken)
+	}
+	slackBot.reactions, err = newFileStore(reactionStoreFile)
+	if err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	slackBot.threads, err = newFileStore(threadStoreFile)
+	if err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	slackBot.creators, err = newFileStore(creatorStoreFile)
+	if err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+	slackBot.drafts, err = newFileStore(draftStoreFile)
+	if err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
 	newIssue := &slacker.CommandDefinition{
 		Description:           "Creates a new issue on github for repo specified",
 		Example:               `new "repo" "issue title" "issue body"`,
 		AuthorizationRequired: false,
 		CustomParser:          slackBot.newIssueParser,
-		Handler:               slackBot.createNewIssue,
+	}
+
+	newThreadIssue := &slacker.CommandDefinition{
+		Description:           "Reply in a thread to create a new issue with the whole thread as its body",
+		Example:               `new "repo" "issue title"`,
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.newThreadIssueParser,
+	}
+
+	templateIssue := &slacker.CommandDefinition{
+		Description:           "Creates a new issue from one of the repo's issue templates or forms",
+		Example:               `new "repo" "issue title" template:bug_report`,
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.templateParser,
+	}
+
+	newConversation := &slacker.CommandDefinition{
+		Description:           "Asks you about a new issue one question at a time, in a DM",
+		Example:               "new",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.conversationParser,
 	}
 
 	registerUser := &slacker.CommandDefinition{
 		Description:           "Associate a github token with a user",
 		AuthorizationRequired: false,
-		Handler:               slackBot.registerUser,
 	}
 
 	deleteUser := &slacker.CommandDefinition{
 		Description:           "Disassociate a github token with a user",
 		AuthorizationRequired: false,
-		Handler:               slackBot.deleteUser,
+	}
+
+	mapUser := &slacker.CommandDefinition{
+		Description:           "(admin) Associate a slack user with a github login",
+		Example:               "map @aj ayjayt",
+		AuthorizationRequired: false,
+	}
+
+	unmapUser := &slacker.CommandDefinition{
+		Description:           "(admin) Remove a slack user from the github login directory",
+		Example:               "unmap @aj",
+		AuthorizationRequired: false,
 	}
 
 	// Register command
-	slackBot.sBot.Command("register <token>", registerUser)
-	slackBot.sBot.Command("unregister", deleteUser)
-	slackBot.sBot.Command("new <repo> <title> <body>", newIssue)
+	slackBot.Command("register <token>", registerUser, slackBot.registerUser)
+	slackBot.Command("unregister", deleteUser, slackBot.deleteUser)
+	slackBot.Command("map <user> <login>", mapUser, slackBot.mapUser)
+	slackBot.Command("unmap <user>", unmapUser, slackBot.unmapUser)
+	slackBot.Command("new <repo> <title> <body>", newIssue, slackBot.createNewIssue)
+	slackBot.Command("new <repo> <title>", newThreadIssue, slackBot.createThreadIssue)
+	slackBot.Command("new <repo> <title> <template>", templateIssue, slackBot.templateIssue)
+	slackBot.Command("new", newConversation, slackBot.startConversation)
+	showIssue := &slacker.CommandDefinition{
+		Description:           "Shows the details of an issue or pull request",
+		Example:               "show gravitational/teleport#4521",
+		AuthorizationRequired: false,
+	}
+	searchIssues := &slacker.CommandDefinition{
+		Description:           "Searches issues in the channel's repo or the default org",
+		Example:               "search is:open atari",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.searchParser,
+	}
+	addComment := &slacker.CommandDefinition{
+		Description:           "Comments on an issue, or on the issue a thread was filed as",
+		Example:               `comment teleport#4521 "comment text"`,
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.commentParser,
+	}
+	closeIssue := &slacker.CommandDefinition{
+		Description:           "Closes an issue, optionally as completed or not planned",
+		Example:               "close teleport#4521 not planned",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.issueCommandParser("close"),
+	}
+	reopenIssue := &slacker.CommandDefinition{
+		Description:           "Reopens an issue",
+		Example:               "reopen teleport#4521",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.issueCommandParser("reopen"),
+	}
+	lockIssue := &slacker.CommandDefinition{
+		Description:           "Locks the conversation on an issue, optionally as off-topic, too heated, resolved or spam",
+		Example:               "lock teleport#4521 too heated",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.issueCommandParser("lock"),
+	}
+	labelIssue := &slacker.CommandDefinition{
+		Description:           "Adds (+) and removes (-) labels on an issue",
+		Example:               "label teleport#4521 +bug -needs-triage",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.issueCommandParser("label"),
+	}
+	assignIssue := &slacker.CommandDefinition{
+		Description:           "Assigns people to an issue, or unassigns them with -",
+		Example:               "assign teleport#4521 @me @jane",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.issueCommandParser("assign"),
+	}
+	editIssue := &slacker.CommandDefinition{
+		Description:           "Changes the title or body of an issue you filed through me",
+		Example:               `edit teleport#4521 title "the new title"`,
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.issueCommandParser("edit"),
+	}
+	undoIssue := &slacker.CommandDefinition{
+		Description:           "Closes, or deletes if you're an admin, the issue you just filed",
+		AuthorizationRequired: false,
+	}
+	moveIssue := &slacker.CommandDefinition{
+		Description:           "Moves an issue to another repo with the same owner",
+		Example:               "move teleport#4521 to teleport-plugins",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.issueCommandParser("move"),
+	}
+	draftIssue := &slacker.CommandDefinition{
+		Description:           "Builds an issue over several messages: draft start, add, show, submit or cancel",
+		Example:               `draft start "repo" "issue title"`,
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.draftParser,
+	}
+	newBatch := &slacker.CommandDefinition{
+		Description:           "Files an issue for each item of the list on the following lines, optionally with a tracking issue",
+		Example:               "new-batch teleport labels=planning tracking=\"Q3 plan\" followed by a list",
+		AuthorizationRequired: false,
+		CustomParser:          slackBot.batchParser,
+	}
+	slackBot.Command("show <ref>", showIssue, slackBot.showIssue)
+	slackBot.Command("search <query>", searchIssues, slackBot.searchIssues)
+	slackBot.Command("comment <ref> <text>", addComment, slackBot.addComment)
+	slackBot.Command("close <ref> <reason>", closeIssue, slackBot.closeIssue)
+	slackBot.Command("reopen <ref>", reopenIssue, slackBot.reopenIssue)
+	slackBot.Command("lock <ref> <reason>", lockIssue, slackBot.lockIssue)
+	slackBot.Command("label <ref> <changes>", labelIssue, slackBot.labelIssue)
+	slackBot.Command("assign <ref> <assignees>", assignIssue, slackBot.assignIssue)
+	slackBot.Command("edit <ref> <field> <value>", editIssue, slackBot.editIssue)
+	slackBot.Command("undo", undoIssue, slackBot.undoCommand)
+	slackBot.Command("move <ref> <repo>", moveIssue, slackBot.moveIssue)
+	slackBot.Command("draft <action> <args>", draftIssue, slackBot.draftCommand)
+	slackBot.Command("new-batch <repo> <list>", newBatch, slackBot.createBatch)
+	slackBot.sBot.DefaultCommand(func(r slacker.Request, w slacker.ResponseWriter) {
+		if slackBot.converse(r, w) {
+			return
+		}
+		slackBot.expandReferences(r)
+	})
+	slackBot.sBot.DefaultEvent(slackBot.handleEvent)
 	slackBot.sBot.Init(func(s *SlackBot) func() {
 		return func() {
 			// TODO: add context
@@ -298,6 +603,14 @@ func newSlackBot(token string, authedUsers []string) *SlackBot {
 	return slackBot
 }
 
+// handleEvent receives the RTM events that aren't messages.
+func (s *SlackBot) handleEvent(event interface{}) {
+	switch ev := event.(type) {
+	case *slack.ReactionAddedEvent:
+		go s.fileReaction(ev)
+	}
+}
+
 // Listen calls Listen on the underlying slackbot
 func (s *SlackBot) Listen(ctx context.Context) error {
 	s.running = true
@@ -309,7 +622,7 @@ func (s *SlackBot) Listen(ctx context.Context) error {
 * The following are helper functions and often write directly to slack
 *************/
 
-func (s *SlackBot) CheckClient(w slacker.ResponseWriter, client *GitHubIssueBot) bool {
+func (s *SlackBot) CheckClient(w responder, client *GitHubIssueBot) bool {
 	if client != nil {
 		return true
 	}
@@ -317,6 +630,25 @@ func (s *SlackBot) CheckClient(w slacker.ResponseWriter, client *GitHubIssueBot)
 	return false
 }
 
+// CheckAdmin reports an error to the user if they aren't listed in --admins.
+func (s *SlackBot) CheckAdmin(r request, w responder) bool {
+	for _, admin := range s.admins {
+		if admin == r.Event().User {
+			return true
+		}
+	}
+	w.ReportError(errors.New("Only admins can do that"))
+	return false
+}
+
+// DefaultOwner is the owner of repos named without one in a channel: the channel's owner setting, or --org.
+func (s *SlackBot) DefaultOwner(channel string) string {
+	if owner := s.settings.Channels[channel].Owner; len(owner) != 0 {
+		return owner
+	}
+	return s.org
+}
+
 // EmptyQueue is called to stop slack commands from starting and locking the program into running.
 // Once all commands are done, the waitgroup can be passed.
 func (s *SlackBot) EmptyQueue() {
@@ -325,21 +657,37 @@ func (s *SlackBot) EmptyQueue() {
 }
 
 // CheckRun will check to see if you should be using the waitgroup.
-func (s *SlackBot) CheckRun(w slacker.ResponseWriter) bool {
+func (s *SlackBot) CheckRun(w responder) bool {
+	if !s.Begin() {
+		w.ReportError(errors.New("I'm shutting down"))
+		return false
+	}
+	return true
+}
 
+// Begin is CheckRun without a ResponseWriter. If it returns true, call Done when finished.
+func (s *SlackBot) Begin() bool {
 	if !s.running { // Don't lock if we're not running
-		w.ReportError(errors.New("I'm shutting down"))
 		return false
 	}
 	s.wg.Add(1)
 	if !s.running { // Unlock if we canceled between the first if statement and now
 		s.wg.Done()
-		w.ReportError(errors.New("I'm shutting down"))
 		return false
 	}
 	return true
 }
 
+// IsAuthorized checks a slack user against the --auth file.
+func (s *SlackBot) IsAuthorized(user string) bool {
+	for _, authed := range s.authedUsers {
+		if authed == user {
+			return true
+		}
+	}
+	return false
+}
+
 // Done is just a wrapper for sync.WaitGroup.Done
 func (s *SlackBot) Done() {
 	s.wg.Done()
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/gravitational/trace"
+)
+
+// fileStore is a map of JSON values that's written to a file on every change.
+// It's meant for small amounts of bot state that should survive a restart.
+type fileStore struct {
+	mu   sync.Mutex
+	file string
+	data map[string]json.RawMessage
+}
+
+// newFileStore creates a fileStore and loads it from file if the file exists.
+func newFileStore(file string) (*fileStore, error) {
+	f := &fileStore{
+		file: file,
+		data: make(map[string]json.RawMessage),
+	}
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return f, trace.Wrap(err)
+	}
+	return f, trace.Wrap(json.Unmarshal(contents, &f.data))
+}
+
+// save writes the store to its file. Caller must hold the lock.
+func (f *fileStore) save() error {
+	contents, err := json.Marshal(f.data)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	return trace.Wrap(ioutil.WriteFile(f.file, contents, 0600))
+}
+
+// Get unmarshals the value at key into value and reports whether it was there.
+func (f *fileStore) Get(key string, value interface{}) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	raw, ok := f.data[key]
+	if !ok {
+		return false, nil
+	}
+	return true, trace.Wrap(json.Unmarshal(raw, value))
+}
+
+// Put stores value at key.
+func (f *fileStore) Put(key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[key] = raw
+	return f.save()
+}
+
+// Delete removes key from the store.
+func (f *fileStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.data[key]; !ok {
+		return nil
+	}
+	delete(f.data, key)
+	return f.save()
+}
+
+// Keys returns every key in the store.
+func (f *fileStore) Keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.data))
+	for key := range f.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
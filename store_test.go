@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	. "gopkg.in/check.v1"
+)
+
+type StoreSuite struct {
+	dir string
+}
+
+var _ = Suite(&StoreSuite{})
+
+func (s *StoreSuite) SetUpTest(c *C) {
+	var err error
+	s.dir, err = ioutil.TempDir("", "issuebot")
+	c.Assert(err, IsNil)
+}
+
+func (s *StoreSuite) TearDownTest(c *C) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *StoreSuite) TestFileStore(c *C) {
+	file := filepath.Join(s.dir, "store")
+	f, err := newFileStore(file)
+	c.Assert(err, IsNil, Commentf("a missing file is an empty store"))
+	c.Assert(f.Keys(), HasLen, 0)
+
+	var url string
+	ok, err := f.Get("C1/1.0", &url)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, false)
+
+	c.Assert(f.Put("C1/1.0", "https://github.com/gravitational/teleport/issues/1"), IsNil)
+	c.Assert(f.Put("C1/2.0", "https://github.com/gravitational/teleport/issues/2"), IsNil)
+	c.Assert(f.Delete("C1/2.0"), IsNil)
+	c.Assert(f.Delete("C1/3.0"), IsNil, Commentf("deleting a missing key is fine"))
+
+	// It survives a reload
+	f, err = newFileStore(file)
+	c.Assert(err, IsNil)
+	ok, err = f.Get("C1/1.0", &url)
+	c.Assert(err, IsNil)
+	c.Assert(ok, Equals, true)
+	c.Assert(url, Equals, "https://github.com/gravitational/teleport/issues/1")
+	keys := f.Keys()
+	sort.Strings(keys)
+	c.Assert(keys, DeepEquals, []string{"C1/1.0"})
+
+	// A value of the wrong type is reported
+	var number int
+	ok, err = f.Get("C1/1.0", &number)
+	c.Assert(ok, Equals, true)
+	c.Assert(err, NotNil)
+}
+
+func (s *StoreSuite) TestCorruptFile(c *C) {
+	file := filepath.Join(s.dir, "store")
+	c.Assert(ioutil.WriteFile(file, []byte("not json"), 0600), IsNil)
+	_, err := newFileStore(file)
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+	"github.com/shomali11/proper"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// templateDir is where github keeps a repo's issue templates and forms
+	templateDir = ".github/ISSUE_TEMPLATE"
+	// templateCallbackID identifies a template modal's submissions
+	templateCallbackID = "template_issue"
+	// fillTemplateActionID identifies the button that opens a template's modal
+	fillTemplateActionID = "template_fill"
+	// noResponse is what github puts under a heading that wasn't filled in
+	noResponse = "_No response_"
+)
+
+var (
+	// templateRegex matches "new <repo> ["title"] template:<name>"
+	templateRegex = regexp.MustCompile(`^\s*(?:<@(\S+)>)?\s*new\s+"?([^\s"]+)"?\s+(?:"([^"\\]*(?:\\.[^"\\]*)*)"\s+)?template:(?:"([^"]+)"|(\S+))\s*$`)
+	// frontMatterRegex splits a markdown template into its YAML front matter and its body
+	frontMatterRegex = regexp.MustCompile(`(?s)^---[ \t]*\r?\n(.*?)\r?\n---[ \t]*(?:\r?\n(.*))?$`)
+	// headingRegex finds a markdown heading
+	headingRegex = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*\s*$`)
+	// htmlCommentRegex finds the comments templates use for instructions
+	htmlCommentRegex = regexp.MustCompile(`(?s)<!--(.*?)-->`)
+)
+
+// issueTemplate is a markdown issue template or an issue form.
+type issueTemplate struct {
+	// File is the template's file name without its extension, eg bug_report
+	File      string
+	Name      string
+	About     string
+	Title     string
+	Labels    []string
+	Assignees []string
+	// Preamble and Sections are a markdown template's body. A template without headings has one section.
+	Preamble string
+	Sections []templateSection
+	// Fields are an issue form's body
+	Fields []formField
+}
+
+// templateSection is a heading in a markdown template and what's under it.
+type templateSection struct {
+	Heading string // the heading as written, eg "## Steps to reproduce"
+	Title   string
+	Hint    string // the template's comments
+	Default string
+}
+
+// formField is a field in an issue form.
+type formField struct {
+	Type        string // markdown, input, textarea, dropdown or checkboxes
+	ID          string
+	Label       string
+	Description string
+	Placeholder string
+	Value       string
+	Render      string
+	Options     []formOption
+	Multiple    bool
+	Required    bool
+}
+
+// formOption is a dropdown's option or a checkbox.
+type formOption struct {
+	Label    string
+	Required bool
+}
+
+// templateHeader is a markdown template's front matter, or a whole issue form.
+type templateHeader struct {
+	Name        string      `yaml:"name"`
+	About       string      `yaml:"about"`
+	Description string      `yaml:"description"`
+	Title       string      `yaml:"title"`
+	Labels      interface{} `yaml:"labels"` // a list, or a comma separated string
+	Assignees   interface{} `yaml:"assignees"`
+	Body        []struct {
+		Type       string `yaml:"type"`
+		ID         string `yaml:"id"`
+		Attributes struct {
+			Label       string        `yaml:"label"`
+			Description string        `yaml:"description"`
+			Placeholder string        `yaml:"placeholder"`
+			Value       string        `yaml:"value"`
+			Render      string        `yaml:"render"`
+			Multiple    bool          `yaml:"multiple"`
+			Options     []interface{} `yaml:"options"`
+		} `yaml:"attributes"`
+		Validations struct {
+			Required bool `yaml:"required"`
+		} `yaml:"validations"`
+	} `yaml:"body"`
+}
+
+// yamlList reads a list that can also be written as a comma separated string.
+func yamlList(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return splitList(value)
+	case []interface{}:
+		var list []string
+		for _, element := range value {
+			if text := strings.TrimSpace(fmt.Sprint(element)); len(text) != 0 {
+				list = append(list, text)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// parseTemplate parses a markdown template or an issue form from templateDir.
+func parseTemplate(file, text string) (*issueTemplate, error) {
+	extension := path.Ext(file)
+	template := &issueTemplate{File: strings.TrimSuffix(file, extension)}
+	var header templateHeader
+	switch strings.ToLower(extension) {
+	case ".md":
+		body := text
+		if match := frontMatterRegex.FindStringSubmatch(text); match != nil {
+			if err := yaml.Unmarshal([]byte(match[1]), &header); err != nil {
+				return nil, trace.BadParameter("%v has bad front matter: %v", file, err)
+			}
+			body = match[2]
+		}
+		template.Preamble, template.Sections = templateSections(body)
+	case ".yml", ".yaml":
+		if err := yaml.Unmarshal([]byte(text), &header); err != nil {
+			return nil, trace.BadParameter("%v isn't a valid issue form: %v", file, err)
+		}
+		if len(header.Body) == 0 {
+			return nil, trace.BadParameter("%v isn't an issue form", file)
+		}
+		for _, element := range header.Body {
+			field := formField{
+				Type:        element.Type,
+				ID:          element.ID,
+				Label:       element.Attributes.Label,
+				Description: element.Attributes.Description,
+				Placeholder: element.Attributes.Placeholder,
+				Value:       element.Attributes.Value,
+				Render:      element.Attributes.Render,
+				Multiple:    element.Attributes.Multiple,
+				Required:    element.Validations.Required,
+			}
+			for _, option := range element.Attributes.Options {
+				// Checkboxes are maps with a label, dropdown options are strings
+				if checkbox, ok := option.(map[interface{}]interface{}); ok {
+					required, _ := checkbox["required"].(bool)
+					field.Options = append(field.Options, formOption{Label: fmt.Sprint(checkbox["label"]), Required: required})
+				} else {
+					field.Options = append(field.Options, formOption{Label: fmt.Sprint(option)})
+				}
+			}
+			template.Fields = append(template.Fields, field)
+		}
+	default:
+		return nil, trace.BadParameter("%v isn't a template", file)
+	}
+	template.Name = header.Name
+	if len(template.Name) == 0 {
+		template.Name = template.File
+	}
+	template.About = header.About + header.Description
+	template.Title = header.Title
+	template.Labels = yamlList(header.Labels)
+	template.Assignees = yamlList(header.Assignees)
+	return template, nil
+}
+
+// parseTemplates parses the templates in a repo's templateDir, skipping config.yml and anything that isn't one.
+func parseTemplates(files []TemplateFile) []*issueTemplate {
+	var templates []*issueTemplate
+	for _, file := range files {
+		if strings.HasPrefix(strings.ToLower(file.Name), "config.") {
+			continue
+		}
+		template, err := parseTemplate(file.Name, file.Text)
+		if err != nil {
+			log.Infof("skipping template: %v", err)
+			continue
+		}
+		templates = append(templates, template)
+	}
+	return templates
+}
+
+// findTemplate finds a template by its file name, eg bug_report, or by its name.
+func findTemplate(templates []*issueTemplate, name string) *issueTemplate {
+	for _, template := range templates {
+		if strings.EqualFold(template.File, name) || strings.EqualFold(template.Name, name) {
+			return template
+		}
+	}
+	return nil
+}
+
+// templateSections splits a markdown template's body at its headings. Headings in code blocks don't count.
+func templateSections(body string) (string, []templateSection) {
+	var preamble []string
+	var sections []templateSection
+	var under []string
+	finish := func() {
+		if len(sections) == 0 {
+			preamble = under
+			return
+		}
+		text := strings.Join(under, "\n")
+		var hints []string
+		for _, comment := range htmlCommentRegex.FindAllStringSubmatch(text, -1) {
+			hints = append(hints, strings.TrimSpace(comment[1]))
+		}
+		last := &sections[len(sections)-1]
+		last.Hint = strings.Join(hints, "\n")
+		last.Default = strings.TrimSpace(htmlCommentRegex.ReplaceAllString(text, ""))
+	}
+	fenced := false
+	for _, line := range strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+		}
+		if match := headingRegex.FindStringSubmatch(line); match != nil && !fenced {
+			finish()
+			sections = append(sections, templateSection{Heading: strings.TrimSpace(line), Title: match[1]})
+			under = nil
+			continue
+		}
+		under = append(under, line)
+	}
+	finish()
+	intro := strings.TrimSpace(htmlCommentRegex.ReplaceAllString(strings.Join(preamble, "\n"), ""))
+	if len(sections) == 0 {
+		// A template without headings is filled in as a whole
+		return "", []templateSection{{Title: "Description", Default: intro}}
+	}
+	return intro, sections
+}
+
+// fillTemplate is a markdown template's body with each heading's answer under it.
+func fillTemplate(template *issueTemplate, answers []string) string {
+	var parts []string
+	if len(template.Preamble) != 0 {
+		parts = append(parts, template.Preamble)
+	}
+	for i, section := range template.Sections {
+		answer := strings.TrimSpace(answers[i])
+		if len(section.Heading) == 0 {
+			parts = append(parts, answer)
+			continue
+		}
+		if len(answer) == 0 {
+			answer = noResponse
+		}
+		parts = append(parts, section.Heading+"\n\n"+answer)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// formBody is an issue form's body the way github writes it, with a heading for each field.
+// answers are each field's values: its text, chosen options or ticked checkboxes.
+func formBody(fields []formField, answers [][]string) string {
+	var parts []string
+	for i, field := range fields {
+		var answer string
+		switch field.Type {
+		case "markdown":
+			continue
+		case "checkboxes":
+			ticked := make(map[string]bool)
+			for _, label := range answers[i] {
+				ticked[label] = true
+			}
+			var boxes []string
+			for _, option := range field.Options {
+				box := "- [ ] "
+				if ticked[option.Label] {
+					box = "- [X] "
+				}
+				boxes = append(boxes, box+option.Label)
+			}
+			answer = strings.Join(boxes, "\n")
+		default:
+			answer = strings.TrimSpace(strings.Join(answers[i], ", "))
+			if len(answer) != 0 && len(field.Render) != 0 {
+				answer = fmt.Sprintf("```%v\n%v\n```", field.Render, answer)
+			}
+		}
+		if len(answer) == 0 {
+			answer = noResponse
+		}
+		parts = append(parts, fmt.Sprintf("### %v\n\n%v", field.Label, answer))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// templateParser matches "new" with a template.
+func (s *SlackBot) templateParser(text string) (*proper.Properties, bool) {
+	resultSlice := templateRegex.FindStringSubmatch(text)
+	if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
+		return nil, false
+	}
+	return proper.NewProperties(map[string]string{
+		"repo":     resultSlice[2],
+		"title":    escapeRegex.ReplaceAllString(resultSlice[3], "$1"),
+		"template": resultSlice[4] + resultSlice[5],
+	}), true
+}
+
+// templateIssue is the callback for "new" with a template. It offers a button that opens the template as a modal.
+func (s *SlackBot) templateIssue(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	repo := r.StringParam("repo", "")
+	if !strings.Contains(repo, "/") {
+		repo = s.DefaultOwner(event.Channel) + "/" + repo
+	}
+	owner, name, err := splitRepo(repo)
+	if err != nil {
+		w.ReportError(ErrBadRepo)
+		return
+	}
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	files, err := client.IssueTemplateFiles(subCtx, owner, name)
+	if err != nil {
+		w.ReportError(fmt.Errorf("Couldn't read the templates in %v", repo))
+		log.Infof("template error: %v", trace.DebugReport(err))
+		return
+	}
+	templates := parseTemplates(files)
+	template := findTemplate(templates, r.StringParam("template", ""))
+	if template == nil {
+		if len(templates) == 0 {
+			w.ReportError(fmt.Errorf("%v doesn't have any issue templates", repo))
+			return
+		}
+		var names []string
+		for _, template := range templates {
+			names = append(names, template.File)
+		}
+		w.ReportError(fmt.Errorf("%v doesn't have that template, try %v", repo, codeList(names)))
+		return
+	}
+
+	id := s.pending.add(pendingIssue{
+		User:     event.User,
+		Channel:  event.Channel,
+		Thread:   event.ThreadTimestamp,
+		Repo:     repo,
+		Title:    s.directory.ToGitHub(r.StringParam("title", "")),
+		Fields:   IssueFields{Labels: template.Labels, Assignees: template.Assignees},
+		Template: template,
+	})
+	text := fmt.Sprintf("Fill in *%v* to file it in %v, within %v minutes", template.Name, repo, PendingIssueMinutes)
+	if len(template.About) != 0 {
+		text += "\n_" + template.About + "_"
+	}
+	fill := slack.NewButtonBlockElement(fillTemplateActionID, id, slack.NewTextBlockObject(slack.PlainTextType, "Fill in", false, false))
+	fill.Style = slack.StylePrimary
+	options := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, slack.NewAccessory(fill))),
+	}
+	if len(event.ThreadTimestamp) != 0 {
+		options = append(options, slack.MsgOptionTS(event.ThreadTimestamp))
+	}
+	if _, err := s.sBot.Client().PostEphemeral(event.Channel, event.User, options...); err != nil {
+		// Eg a slash command in a channel the bot isn't in, where the button can't be shown
+		log.Infof("couldn't post template button: %v", err)
+		s.pending.take(id, event.User)
+		w.Reply("Use templates from a channel the bot is in")
+	}
+}
+
+// fillTemplateButton opens a template's modal when its button is clicked.
+func (s *SlackBot) fillTemplateButton(ctx context.Context, payload *interaction, id string) {
+	if !s.Begin() {
+		return
+	}
+	defer s.Done()
+	reply := func(text string) {
+		if err := postResponseURL(payload.ResponseURL, slashResponse{Text: text}); err != nil {
+			log.Errorf("couldn't post to response_url: %v", trace.DebugReport(err))
+		}
+	}
+	// The issue stays pending until the modal is submitted, so a closed modal can be opened again
+	issue, ok := s.pending.get(id, payload.User.ID)
+	if !ok || issue.Template == nil {
+		reply("*Error:* _That template has expired, use `new` again_")
+		return
+	}
+	if err := s.openTemplateModal(ctx, payload.TriggerID, id, issue); err != nil {
+		log.Infof("couldn't open template modal: %v", trace.DebugReport(err))
+		reply(fmt.Sprintf("*Error:* _Couldn't open the form: %v_", trace.UserMessage(err)))
+	}
+}
+
+// fieldBlockID is the block_id of a template modal's input for a section or field.
+func fieldBlockID(i int) string {
+	return "field_" + strconv.Itoa(i)
+}
+
+// clip shortens text to slack's limit for a part of a block.
+func clip(text string, max int) string {
+	if runes := []rune(text); len(runes) > max {
+		return string(runes[:max-1]) + "…"
+	}
+	return text
+}
+
+// templateInput is a modal input for a form field, or nil for fields that are only text.
+func templateInput(i int, field formField) *inputBlock {
+	element := &blockElement{Type: "plain_text_input", InitialValue: clip(field.Value, 3000)}
+	if len(field.Placeholder) != 0 {
+		element.Placeholder = plainText(clip(field.Placeholder, 150))
+	}
+	switch field.Type {
+	case "markdown":
+		return nil
+	case "textarea":
+		element.Multiline = true
+	case "dropdown":
+		element = &blockElement{Type: "static_select", Placeholder: plainText("Choose")}
+		if field.Multiple {
+			element.Type = "multi_static_select"
+		}
+	case "checkboxes":
+		element = &blockElement{Type: "checkboxes"}
+	}
+	if field.Type == "dropdown" || field.Type == "checkboxes" {
+		for j, option := range field.Options {
+			element.Options = append(element.Options, &optionObject{Text: plainText(clip(option.Label, 75)), Value: strconv.Itoa(j)})
+		}
+	}
+	required := field.Required
+	if field.Type == "checkboxes" {
+		required = false
+		for _, option := range field.Options {
+			required = required || option.Required
+		}
+	}
+	block := newInputBlock(fieldBlockID(i), clip(field.Label, 2000), !required, element)
+	if len(field.Description) != 0 {
+		block.Hint = plainText(clip(field.Description, 2000))
+	}
+	return block
+}
+
+// openTemplateModal opens a modal with an input for each of the template's headings or fields.
+func (s *SlackBot) openTemplateModal(ctx context.Context, triggerID, id string, issue pendingIssue) error {
+	template := issue.Template
+	title := issue.Title
+	if len(title) == 0 {
+		title = template.Title
+	}
+	blocks := []interface{}{
+		newInputBlock("title", "Title", false, &blockElement{Type: "plain_text_input", InitialValue: title}),
+	}
+	for i, section := range template.Sections {
+		block := newInputBlock(fieldBlockID(i), clip(section.Title, 2000), true,
+			&blockElement{Type: "plain_text_input", Multiline: true, InitialValue: clip(section.Default, 3000)})
+		if len(section.Hint) != 0 {
+			block.Hint = plainText(clip(section.Hint, 2000))
+		}
+		blocks = append(blocks, block)
+	}
+	for i, field := range template.Fields {
+		if block := templateInput(i, field); block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+
+	metadata, err := json.Marshal(modalMetadata{Channel: issue.Channel, Pending: id})
+	if err != nil {
+		return trace.Wrap(err)
+	}
+	modal := view{
+		Type:            "modal",
+		CallbackID:      templateCallbackID,
+		PrivateMetadata: string(metadata),
+		Title:           plainText(clip(template.Name, 24)),
+		Submit:          plainText("Create"),
+		Close:           plainText("Cancel"),
+		Blocks:          blocks,
+	}
+	return trace.Wrap(s.slackAPI(ctx, "views.open", map[string]interface{}{
+		"trigger_id": triggerID,
+		"view":       modal,
+	}, nil))
+}
+
+// submitTemplateModal files the issue from a submitted template modal, with the template's labels and
+// assignees. Like submitIssueModal, it returns validation errors by block_id, or files the issue after
+// the modal closes.
+func (s *SlackBot) submitTemplateModal(ctx context.Context, user string, submitted *viewState) map[string]string {
+	var metadata modalMetadata
+	if err := json.Unmarshal([]byte(submitted.PrivateMetadata), &metadata); err != nil {
+		log.Infof("bad modal metadata %q: %v", submitted.PrivateMetadata, err)
+	}
+	issue, ok := s.pending.get(metadata.Pending, user)
+	if !ok || issue.Template == nil {
+		return map[string]string{"title": "This form has expired, use new again"}
+	}
+	template := issue.Template
+	title := submitted.value("title").text()
+	validation := make(map[string]string)
+	if len(title) == 0 {
+		validation["title"] = "An issue needs a title"
+	}
+
+	var body string
+	if len(template.Fields) != 0 {
+		answers := make([][]string, len(template.Fields))
+		for i, field := range template.Fields {
+			value := submitted.value(fieldBlockID(i))
+			if field.Type == "dropdown" || field.Type == "checkboxes" {
+				chosen := value.SelectedOpts
+				if value.SelectedOption != nil {
+					chosen = append(chosen, *value.SelectedOption)
+				}
+				picked := make(map[string]bool)
+				for _, option := range chosen {
+					if j, err := strconv.Atoi(option.Value); err == nil && j >= 0 && j < len(field.Options) {
+						answers[i] = append(answers[i], field.Options[j].Label)
+						picked[field.Options[j].Label] = true
+					}
+				}
+				for _, option := range field.Options {
+					if field.Type == "checkboxes" && option.Required && !picked[option.Label] {
+						validation[fieldBlockID(i)] = fmt.Sprintf("Tick %q", clip(option.Label, 75))
+					}
+				}
+				continue
+			}
+			if text := value.text(); len(text) != 0 {
+				answers[i] = []string{s.directory.ToGitHub(text)}
+			}
+		}
+		body = formBody(template.Fields, answers)
+	} else {
+		answers := make([]string, len(template.Sections))
+		for i := range template.Sections {
+			answers[i] = s.directory.ToGitHub(submitted.value(fieldBlockID(i)).text())
+		}
+		body = fillTemplate(template, answers)
+	}
+	if len(validation) != 0 {
+		return validation
+	}
+	if _, ok := s.pending.take(metadata.Pending, user); !ok {
+		return map[string]string{"title": "This form has expired, use new again"}
+	}
+
+	go func() {
+		if !s.Begin() {
+			return
+		}
+		defer s.Done()
+		var result string
+		client := s.getGBotForUser(ctx, user)
+		if client == nil {
+			result = "You must register first, see `help` command"
+		} else {
+			subCtx, cancel := context.WithTimeout(ctx, time.Second*TimeoutSeconds)
+			defer cancel()
+			// A template's labels and assignees can be out of date, which shouldn't stop the issue being filed
+			fields, dropped, err := client.KnownFields(subCtx, issue.Repo, issue.Fields)
+			if err != nil {
+				log.Infof("couldn't check template %v's labels and assignees: %v", template.File, trace.DebugReport(err))
+			}
+			if len(dropped) != 0 {
+				log.Infof("template %v has an unknown %v in %v", template.File, strings.Join(dropped, ", "), issue.Repo)
+			}
+			body := withAttribution(body, s.userAttribution(subCtx, user, "Filed"))
+			created, err := client.NewIssue(subCtx, issue.Repo, s.directory.ToGitHub(title), body, fields)
+			if err != nil {
+				log.Infof("template issue error: %v", trace.DebugReport(err))
+				result = fmt.Sprintf("Couldn't create your issue in %v: %v", issue.Repo, trace.UserMessage(err))
+			} else {
+				s.recordIssue(user, issue.Channel, issue.Thread, created)
+				result = created.Url
+				if len(dropped) != 0 {
+					result += fmt.Sprintf("\n_The template's %v isn't in %v, so it was left out_", strings.Join(dropped, ", "), issue.Repo)
+				}
+			}
+		}
+		s.notify(user, issue.Channel, result)
+	}()
+	return nil
+}
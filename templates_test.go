@@ -0,0 +1,103 @@
+package main
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type TemplatesSuite struct{}
+
+var _ = Suite(&TemplatesSuite{})
+
+func (s *TemplatesSuite) TestTemplateParser(c *C) {
+	bot := &SlackBot{botID: "UBOT"}
+	properties, ok := bot.templateParser(`<@UBOT> new "teleport" "tsh crashes" template:bug_report`)
+	c.Assert(ok, Equals, true)
+	c.Assert(properties.StringParam("repo", ""), Equals, "teleport")
+	c.Assert(properties.StringParam("title", ""), Equals, "tsh crashes")
+	c.Assert(properties.StringParam("template", ""), Equals, "bug_report")
+
+	properties, ok = bot.templateParser(`new gravitational/teleport template:"Feature request"`)
+	c.Assert(ok, Equals, true)
+	c.Assert(properties.StringParam("title", ""), Equals, "")
+	c.Assert(properties.StringParam("template", ""), Equals, "Feature request")
+
+	_, ok = bot.templateParser(`new "teleport" "title" "body"`)
+	c.Assert(ok, Equals, false)
+}
+
+func (s *TemplatesSuite) TestMarkdownTemplate(c *C) {
+	text := "---\nname: Bug report\nabout: Something isn't working\ntitle: \"[BUG] \"\nlabels: bug, needs-triage\nassignees:\n  - aj\n---\n" +
+		"<!-- Search for duplicates first -->\n" +
+		"## Expected behavior\n<!-- What should happen? -->\n\n" +
+		"## Steps to reproduce\n1.\n```\n# not a heading\n```\n"
+	template, err := parseTemplate("bug_report.md", text)
+	c.Assert(err, IsNil)
+	c.Assert(template.File, Equals, "bug_report")
+	c.Assert(template.Name, Equals, "Bug report")
+	c.Assert(template.Title, Equals, "[BUG] ")
+	c.Assert(template.Labels, DeepEquals, []string{"bug", "needs-triage"})
+	c.Assert(template.Assignees, DeepEquals, []string{"aj"})
+	c.Assert(template.Preamble, Equals, "")
+	c.Assert(template.Sections, DeepEquals, []templateSection{
+		{Heading: "## Expected behavior", Title: "Expected behavior", Hint: "What should happen?"},
+		{Heading: "## Steps to reproduce", Title: "Steps to reproduce", Default: "1.\n```\n# not a heading\n```"},
+	})
+	c.Assert(fillTemplate(template, []string{"It works", ""}), Equals,
+		"## Expected behavior\n\nIt works\n\n## Steps to reproduce\n\n_No response_")
+
+	template, err = parseTemplate("plain.md", "Describe the problem")
+	c.Assert(err, IsNil)
+	c.Assert(template.Sections, DeepEquals, []templateSection{{Title: "Description", Default: "Describe the problem"}})
+	c.Assert(fillTemplate(template, []string{"It's broken"}), Equals, "It's broken")
+}
+
+func (s *TemplatesSuite) TestIssueForm(c *C) {
+	text := `name: Bug report
+description: File a bug
+labels: [bug]
+body:
+  - type: markdown
+    attributes:
+      value: Thanks for taking the time!
+  - type: textarea
+    id: logs
+    attributes:
+      label: Logs
+      render: shell
+    validations:
+      required: true
+  - type: dropdown
+    id: version
+    attributes:
+      label: Version
+      options:
+        - "13"
+        - "14"
+  - type: checkboxes
+    attributes:
+      label: Code of conduct
+      options:
+        - label: I agree
+          required: true
+        - label: I searched
+`
+	template, err := parseTemplate("bug.yml", text)
+	c.Assert(err, IsNil)
+	c.Assert(template.Name, Equals, "Bug report")
+	c.Assert(template.About, Equals, "File a bug")
+	c.Assert(template.Labels, DeepEquals, []string{"bug"})
+	c.Assert(template.Fields, HasLen, 4)
+	c.Assert(template.Fields[1].Required, Equals, true)
+	c.Assert(template.Fields[3].Options, DeepEquals, []formOption{{Label: "I agree", Required: true}, {Label: "I searched"}})
+
+	body := formBody(template.Fields, [][]string{nil, {"panic"}, nil, {"I agree"}})
+	c.Assert(body, Equals, "### Logs\n\n```shell\npanic\n```\n\n### Version\n\n_No response_\n\n"+
+		"### Code of conduct\n\n- [X] I agree\n- [ ] I searched")
+
+	_, err = parseTemplate("config.yml", "blank_issues_enabled: false")
+	c.Assert(err, NotNil)
+	templates := parseTemplates([]TemplateFile{{Name: "config.yml"}, {Name: "bug.yml", Text: text}})
+	c.Assert(templates, HasLen, 1)
+	c.Assert(findTemplate(templates, "bug report"), Equals, templates[0])
+	c.Assert(findTemplate(templates, "feature"), IsNil)
+}
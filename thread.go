@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+	"github.com/shomali11/proper"
+)
+
+const (
+	// ThreadTimeoutSeconds is how much time the bot gives Slack to return a whole thread
+	ThreadTimeoutSeconds = 20
+	// threadStoreFile is where threads are linked to the issues filed from them
+	threadStoreFile = "./threads"
+)
+
+var (
+	// threadIssueRegex will find two quoted strings, see issueRegex
+	threadIssueRegex = regexp.MustCompile(`^\s*(?:<@(\S+)>)?\s*new\s+"([^"\\]*(?:\\.[^"\\]*)*)"\s+"([^"\\]*(?:\\.[^"\\]*)*)"\s*$`)
+)
+
+// newThreadIssueParser matches the thread variant of "new", which has a repo and a title but no body.
+func (s *SlackBot) newThreadIssueParser(text string) (*proper.Properties, bool) {
+	resultSlice := threadIssueRegex.FindStringSubmatch(text)
+	if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
+		return nil, false
+	}
+	deEscape := func(escaped string) string { return escapeRegex.ReplaceAllString(escaped, "$1") }
+	parameters := make(map[string]string)
+	parameters["repo"] = deEscape(resultSlice[2])
+	parameters["title"] = deEscape(resultSlice[3])
+	return proper.NewProperties(parameters), true
+}
+
+// createThreadIssue is the callback for "new" with no body, used as a reply in a thread or with shared files.
+// The whole thread, and the files, become the body of the issue, and the issue's url is posted back to the thread
+// with an Undo button, like new's.
+func (s *SlackBot) createThreadIssue(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	event := r.Event()
+	if len(event.ThreadTimestamp) == 0 && len(event.Files) == 0 {
+		w.ReportError(errors.New(`Without a body, "new" has to be a reply in a thread or share a file`))
+		return
+	}
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+	repo := r.StringParam("repo", "")
+	title := s.directory.ToGitHub(r.StringParam("title", ""))
+
+	var body string
+	if len(event.ThreadTimestamp) != 0 {
+		threadCtx, cancelThread := context.WithTimeout(r.Context(), time.Second*ThreadTimeoutSeconds)
+		defer cancelThread()
+		var err error
+		body, err = s.threadToBody(threadCtx, event.Channel, event.ThreadTimestamp, event.Timestamp)
+		if err != nil {
+			w.ReportError(errors.New("Couldn't read the thread, check the logs"))
+			log.Infof("thread issue error: %v", trace.DebugReport(err))
+			return
+		}
+	}
+	if len(event.Files) != 0 {
+		budget := MaxFileBytes - len(body)
+		body = strings.TrimLeft(body+"\n\n"+s.filesToMarkdown(event.Files, &budget), "\n")
+	}
+
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	body = withAttribution(body, s.commandAttribution(subCtx, r, "Filed"))
+	draft := pendingIssue{Repo: repo, Title: title, Body: body}
+	if s.previewIssue(r, w, draft) || s.checkDuplicates(subCtx, r, w, client, draft) {
+		return
+	}
+	issue, err := client.NewIssue(subCtx, repo, title, body, IssueFields{})
+	if err != nil {
+		w.ReportError(errors.New("There was an error with the GitHub interface... Check 1) the repo name 2) the logs"))
+		log.Infof("thread issue error: %v", trace.DebugReport(err))
+		return
+	}
+	s.recordIssue(event.User, event.Channel, event.ThreadTimestamp, issue)
+	s.postNewIssue(r, w, issue)
+}
+
+// linkThread remembers that a thread is about an issue, so that replies in it can refer to the issue.
+func (s *SlackBot) linkThread(channel, threadTS string, ref issueRef) {
+	if len(threadTS) == 0 {
+		return
+	}
+	if err := s.threads.Put(channel+"/"+threadTS, ref.String()); err != nil {
+		log.Errorf(trace.DebugReport(err))
+	}
+}
+
+// threadIssue finds the issue a thread is linked to.
+func (s *SlackBot) threadIssue(channel, threadTS string) (issueRef, bool) {
+	var ref string
+	if len(threadTS) == 0 {
+		return issueRef{}, false
+	}
+	if ok, err := s.threads.Get(channel+"/"+threadTS, &ref); !ok || err != nil {
+		return issueRef{}, false
+	}
+	return parseIssueRef(ref, "")
+}
+
+// relinkThreads points the threads linked to one issue at another, eg after the issue moves.
+func (s *SlackBot) relinkThreads(from, to issueRef) {
+	for _, key := range s.threads.Keys() {
+		var ref string
+		if ok, err := s.threads.Get(key, &ref); !ok || err != nil || ref != from.String() {
+			continue
+		}
+		if err := s.threads.Put(key, to.String()); err != nil {
+			log.Errorf(trace.DebugReport(err))
+		}
+	}
+}
+
+// replyInThread replies in the command's thread, if it's in one.
+func (s *SlackBot) replyInThread(r request, w responder, text string) {
+	event := r.Event()
+	if len(event.ThreadTimestamp) == 0 {
+		w.Reply(text)
+		return
+	}
+	_, _, err := s.sBot.Client().PostMessageContext(r.Context(), event.Channel,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionTS(event.ThreadTimestamp),
+		slack.MsgOptionDisableLinkUnfurl())
+	if err != nil {
+		log.Errorf("couldn't reply in thread: %v", trace.DebugReport(err))
+		w.Reply(text)
+	}
+}
+
+// threadToBody collects every message in a thread, except the one at skipTS, into a markdown issue body.
+func (s *SlackBot) threadToBody(ctx context.Context, channel, threadTS, skipTS string) (string, error) {
+	api := s.sBot.Client()
+	var messages []slack.Message
+	params := &slack.GetConversationRepliesParameters{
+		ChannelID: channel,
+		Timestamp: threadTS,
+	}
+	for {
+		page, hasMore, cursor, err := api.GetConversationRepliesContext(ctx, params)
+		if err != nil {
+			return "", trace.Wrap(err)
+		}
+		messages = append(messages, page...)
+		if !hasMore || len(cursor) == 0 {
+			break
+		}
+		params.Cursor = cursor
+	}
+
+	threadLink, err := api.GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: channel, Ts: threadTS})
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+
+	return s.threadMarkdown(ctx, channel, threadTS, threadLink, messages, skipTS, make(map[string]string)), nil
+}
+
+// threadMarkdown turns a thread's messages, except the one at skipTS, into a markdown issue body. names
+// caches who wrote them, see authorName.
+func (s *SlackBot) threadMarkdown(ctx context.Context, channel, threadTS, threadLink string, messages []slack.Message,
+	skipTS string, names map[string]string) string {
+	budget := MaxFileBytes // shared by every message's files
+	var body strings.Builder
+	fmt.Fprintf(&body, "_Filed from a [Slack thread](%v)_\n", threadLink)
+	for _, message := range messages {
+		if message.Timestamp == skipTS {
+			continue
+		}
+		fmt.Fprintf(&body, "\n---\n**%v** · %v · [permalink](%v)\n\n",
+			s.authorName(ctx, message, names), slackTime(message.Timestamp), messageLink(threadLink, channel, threadTS, message.Timestamp))
+		if len(message.Text) != 0 {
+			body.WriteString(s.toGitHub(message.Text))
+			body.WriteString("\n")
+		}
+		for _, attachment := range message.Attachments {
+			text := attachment.Text
+			if len(text) == 0 {
+				text = attachment.Fallback
+			}
+			fmt.Fprintf(&body, "\n> **%v** %v\n", attachment.Title, strings.Replace(text, "\n", "\n> ", -1))
+		}
+		if len(message.Files) != 0 {
+			fmt.Fprintf(&body, "\n%v\n", s.filesToMarkdown(message.Files, &budget))
+		}
+	}
+	return body.String()
+}
+
+// messageLink builds the permalink of a message in a thread from the thread's permalink, eg
+// https://example.slack.com/archives/C0123ABCD/p1549412640000200, so that a long thread doesn't need a
+// lookup for every message. If threadLink isn't in that form, it's used for every message.
+func messageLink(threadLink, channel, threadTS, ts string) string {
+	base := threadLink
+	if i := strings.Index(base, "?"); i >= 0 {
+		base = base[:i]
+	}
+	root := "/p" + strings.Replace(threadTS, ".", "", 1)
+	if !strings.HasSuffix(base, root) {
+		return threadLink
+	}
+	if ts == threadTS {
+		return base
+	}
+	return fmt.Sprintf("%v/p%v?thread_ts=%v&cid=%v", strings.TrimSuffix(base, root), strings.Replace(ts, ".", "", 1), threadTS, channel)
+}
+
+// authorName describes who wrote a message, using names as a cache of slack user lookups.
+func (s *SlackBot) authorName(ctx context.Context, message slack.Message, names map[string]string) string {
+	if len(message.User) == 0 {
+		return message.Username // bots
+	}
+	if name, ok := names[message.User]; ok {
+		return name
+	}
+	name := message.User
+	if user, err := s.sBot.Client().GetUserInfoContext(ctx, message.User); err == nil {
+		name = user.RealName
+	} else {
+		log.Infof("couldn't look up %v: %v", message.User, err)
+	}
+	if login, ok := s.directory.Login(message.User); ok {
+		name = fmt.Sprintf("%v (@%v)", name, login)
+	}
+	names[message.User] = name
+	return name
+}
+
+// slackTime formats a slack timestamp, eg "1549412640.000200", as a UTC time.
+func slackTime(ts string) string {
+	seconds, err := strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return ts
+	}
+	return time.Unix(int64(seconds), 0).UTC().Format("2006-01-02 15:04 MST")
+}
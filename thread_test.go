@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/nlopes/slack"
+	. "gopkg.in/check.v1"
+)
+
+type ThreadSuite struct{}
+
+var _ = Suite(&ThreadSuite{})
+
+func (s *ThreadSuite) TestNewThreadIssueParser(c *C) {
+	bot := &SlackBot{botID: "UBOT"}
+	testTables := []struct {
+		name  string
+		text  string
+		ok    bool
+		repo  string
+		title string
+	}{
+		{name: "Plain", text: `new "teleport" "tsh hangs"`, ok: true, repo: "teleport", title: "tsh hangs"},
+		{name: "Mention", text: `<@UBOT> new "gravitational/teleport" "tsh hangs"`, ok: true, repo: "gravitational/teleport", title: "tsh hangs"},
+		{name: "Escaped Quote", text: `new "teleport" "tsh \"login\" hangs"`, ok: true, repo: "teleport", title: `tsh "login" hangs`},
+		{name: "Other Bot", text: `<@UOTHER> new "teleport" "tsh hangs"`},
+		{name: "With Body", text: `new "teleport" "tsh hangs" "on login"`},
+		{name: "No Title", text: `new "teleport"`},
+	}
+	for i, tt := range testTables {
+		comment := Commentf("test #%d (%v)", i+1, tt.name)
+		properties, ok := bot.newThreadIssueParser(tt.text)
+		c.Assert(ok, Equals, tt.ok, comment)
+		if !tt.ok {
+			continue
+		}
+		c.Assert(properties.StringParam("repo", ""), Equals, tt.repo, comment)
+		c.Assert(properties.StringParam("title", ""), Equals, tt.title, comment)
+	}
+}
+
+func (s *ThreadSuite) TestSlackTime(c *C) {
+	c.Assert(slackTime("1549412640.000200"), Equals, "2019-02-06 00:24 UTC")
+	c.Assert(slackTime("not a time"), Equals, "not a time")
+}
+
+func (s *ThreadSuite) TestMessageLink(c *C) {
+	threadLink := "https://example.slack.com/archives/C0123ABCD/p1549412640000200"
+	testTables := []struct {
+		name       string
+		threadLink string
+		ts         string
+		link       string
+	}{
+		{name: "Root", threadLink: threadLink, ts: "1549412640.000200", link: threadLink},
+		{name: "Reply", threadLink: threadLink, ts: "1549412700.000300",
+			link: "https://example.slack.com/archives/C0123ABCD/p1549412700000300?thread_ts=1549412640.000200&cid=C0123ABCD"},
+		{name: "Root With Query", threadLink: threadLink + "?cid=C0123ABCD", ts: "1549412640.000200", link: threadLink},
+		{name: "Unknown Form", threadLink: "https://example.com/thread", ts: "1549412700.000300", link: "https://example.com/thread"},
+	}
+	for i, tt := range testTables {
+		c.Assert(messageLink(tt.threadLink, "C0123ABCD", "1549412640.000200", tt.ts), Equals, tt.link, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
+
+func (s *ThreadSuite) TestThreadMarkdown(c *C) {
+	directory, err := newUserDirectory(filepath.Join(c.MkDir(), "directory"))
+	c.Assert(err, IsNil)
+	c.Assert(directory.Set("U1234ABCD", "ayjayt", false), IsNil)
+	bot := &SlackBot{directory: directory}
+
+	message := func(ts, user, text string) slack.Message {
+		m := slack.Message{}
+		m.Timestamp = ts
+		m.User = user
+		m.Text = text
+		return m
+	}
+	attached := message("1549412760.000400", "", "")
+	attached.Username = "alertbot"
+	attached.Attachments = []slack.Attachment{{Title: "Alert", Text: "disk full\non node-1"}}
+	messages := []slack.Message{
+		message("1549412640.000200", "U1234ABCD", "tsh *hangs* on login, cc <@U1234ABCD>"),
+		message("1549412700.000300", "U5678EFGH", "same here"),
+		attached,
+		message("1549412820.000500", "U1234ABCD", `new "teleport" "tsh hangs"`),
+	}
+	// Names are looked up once per author, so seeding them keeps slack out of the test
+	names := map[string]string{"U1234ABCD": "AJ (@ayjayt)", "U5678EFGH": "Sasha"}
+	body := bot.threadMarkdown(context.Background(), "C0123ABCD", "1549412640.000200",
+		"https://example.slack.com/archives/C0123ABCD/p1549412640000200", messages, "1549412820.000500", names)
+	c.Assert(body, Equals, "_Filed from a [Slack thread](https://example.slack.com/archives/C0123ABCD/p1549412640000200)_\n"+
+		"\n---\n**AJ (@ayjayt)** · 2019-02-06 00:24 UTC · [permalink](https://example.slack.com/archives/C0123ABCD/p1549412640000200)\n\n"+
+		"tsh **hangs** on login, cc @ayjayt\n"+
+		"\n---\n**Sasha** · 2019-02-06 00:25 UTC · [permalink](https://example.slack.com/archives/C0123ABCD/p1549412700000300?thread_ts=1549412640.000200&cid=C0123ABCD)\n\n"+
+		"same here\n"+
+		"\n---\n**alertbot** · 2019-02-06 00:26 UTC · [permalink](https://example.slack.com/archives/C0123ABCD/p1549412760000400?thread_ts=1549412640.000200&cid=C0123ABCD)\n\n"+
+		"\n> **Alert** disk full\n> on node-1\n")
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/shomali11/proper"
+	"github.com/shurcooL/githubv4"
+)
+
+var (
+	// closeReasons are the reasons an issue can be closed for
+	closeReasons = map[string]string{
+		"completed":   "COMPLETED",
+		"not planned": "NOT_PLANNED",
+	}
+	// lockReasons are the reasons a conversation can be locked for
+	lockReasons = map[string]githubv4.LockReason{
+		"off-topic":  githubv4.LockReasonOffTopic,
+		"too heated": githubv4.LockReasonTooHeated,
+		"resolved":   githubv4.LockReasonResolved,
+		"spam":       githubv4.LockReasonSpam,
+	}
+	// triagePermissions are the repo permissions that can close, reopen and lock issues
+	triagePermissions = map[string]bool{"ADMIN": true, "MAINTAIN": true, "WRITE": true, "TRIAGE": true}
+)
+
+// issueCommandParser makes a parser for "<verb> [owner/repo#N] [args]", a command about one issue.
+// Without an issue the command is about the issue its thread is linked to, see commandIssue.
+func (s *SlackBot) issueCommandParser(verb string) func(string) (*proper.Properties, bool) {
+	verbRegex := regexp.MustCompile(`^\s*(?:<@(\S+)>)?\s*` + verb + `(?:\s+(.*?))?\s*$`)
+	return func(text string) (*proper.Properties, bool) {
+		resultSlice := verbRegex.FindStringSubmatch(text)
+		if resultSlice == nil || (len(resultSlice[1]) != 0 && resultSlice[1] != s.botID) {
+			return nil, false
+		}
+		parameters := map[string]string{"ref": "", "args": resultSlice[2]}
+		if fields := strings.Fields(resultSlice[2]); len(fields) != 0 && (strings.Contains(fields[0], "#") || strings.HasPrefix(fields[0], "<http")) {
+			parameters["ref"] = fields[0]
+			parameters["args"] = strings.TrimSpace(strings.TrimPrefix(resultSlice[2], fields[0]))
+		}
+		return proper.NewProperties(parameters), true
+	}
+}
+
+// codeList formats names, eg reasons or labels, as a list of code.
+func codeList(names []string) string {
+	return "`" + strings.Join(names, "`, `") + "`"
+}
+
+// triage runs a command that needs triage permission on an issue's repo, and replies with what action returns.
+func (s *SlackBot) triage(r request, w responder, action func(ctx context.Context, client *GitHubIssueBot, ref issueRef) (string, error)) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	ref, ok := s.commandIssue(r)
+	if !ok {
+		w.ReportError(ErrNoIssue)
+		return
+	}
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+
+	subCtx, cancel := context.WithTimeout(r.Context(), time.Second*TimeoutSeconds)
+	defer cancel()
+	permission, err := client.ViewerPermission(subCtx, ref.Owner, ref.Repo)
+	if err != nil {
+		w.ReportError(fmt.Errorf("Couldn't find %v/%v", ref.Owner, ref.Repo))
+		log.Infof("permission error: %v", trace.DebugReport(err))
+		return
+	}
+	if !triagePermissions[permission] {
+		w.ReportError(fmt.Errorf("You need triage permission on %v/%v", ref.Owner, ref.Repo))
+		return
+	}
+	result, err := action(subCtx, client, ref)
+	if err != nil {
+		w.ReportError(fmt.Errorf("That didn't work on %v", ref))
+		log.Infof("triage error: %v", trace.DebugReport(err))
+		return
+	}
+	s.audit(r.Event().User, "triage", ref, result)
+	s.replyInThread(r, w, result)
+}
+
+// closeIssue is the callback for the "close" command.
+func (s *SlackBot) closeIssue(r request, w responder) {
+	args := strings.ToLower(r.StringParam("args", ""))
+	reason, ok := closeReasons[strings.Replace(args, "_", " ", -1)]
+	if len(args) != 0 && !ok {
+		w.ReportError(fmt.Errorf("Issues can be closed as %v", codeList([]string{"completed", "not planned"})))
+		return
+	}
+	s.triage(r, w, func(ctx context.Context, client *GitHubIssueBot, ref issueRef) (string, error) {
+		state, err := client.CloseIssue(ctx, ref, reason)
+		if err != nil {
+			return "", trace.Wrap(err)
+		}
+		if len(args) != 0 {
+			return fmt.Sprintf("%v is %v as %v", ref, strings.ToLower(state), args), nil
+		}
+		return fmt.Sprintf("%v is %v", ref, strings.ToLower(state)), nil
+	})
+}
+
+// reopenIssue is the callback for the "reopen" command.
+func (s *SlackBot) reopenIssue(r request, w responder) {
+	s.triage(r, w, func(ctx context.Context, client *GitHubIssueBot, ref issueRef) (string, error) {
+		state, err := client.ReopenIssue(ctx, ref)
+		if err != nil {
+			return "", trace.Wrap(err)
+		}
+		return fmt.Sprintf("%v is %v", ref, strings.ToLower(state)), nil
+	})
+}
+
+// lockIssue is the callback for the "lock" command.
+func (s *SlackBot) lockIssue(r request, w responder) {
+	args := strings.ToLower(r.StringParam("args", ""))
+	reason, ok := lockReasons[strings.Replace(args, "_", " ", -1)]
+	if len(args) != 0 && !ok {
+		w.ReportError(fmt.Errorf("Conversations can be locked as %v", codeList([]string{"off-topic", "too heated", "resolved", "spam"})))
+		return
+	}
+	s.triage(r, w, func(ctx context.Context, client *GitHubIssueBot, ref issueRef) (string, error) {
+		if err := client.LockIssue(ctx, ref, reason); err != nil {
+			return "", trace.Wrap(err)
+		}
+		if len(args) != 0 {
+			return fmt.Sprintf("%v is locked as %v", ref, args), nil
+		}
+		return fmt.Sprintf("%v is locked", ref), nil
+	})
+}
+
+// parseChanges splits "+bug -needs-triage docs" into names to add and remove. Names without + or - are added.
+func parseChanges(args string) IssueChanges {
+	var changes IssueChanges
+	for _, field := range strings.Fields(args) {
+		switch {
+		case field == "-" || field == "+":
+			continue
+		case strings.HasPrefix(field, "-"):
+			changes.Remove = append(changes.Remove, field[1:])
+		case strings.HasPrefix(field, "+"):
+			changes.Add = append(changes.Add, field[1:])
+		default:
+			changes.Add = append(changes.Add, field)
+		}
+	}
+	return changes
+}
+
+// describeChanges says which changes took effect on an issue and which names were unknown,
+// formatting lists of names with list.
+func describeChanges(ref issueRef, changes IssueChanges, unknown []string, kind string, list func([]string) string) string {
+	isUnknown := make(map[string]bool)
+	for _, name := range unknown {
+		isUnknown[name] = true
+	}
+	known := func(names []string) []string {
+		var changed []string
+		for _, name := range names {
+			if !isUnknown[name] {
+				changed = append(changed, name)
+			}
+		}
+		return changed
+	}
+	var parts []string
+	if added := known(changes.Add); len(added) != 0 {
+		parts = append(parts, "added "+list(added))
+	}
+	if removed := known(changes.Remove); len(removed) != 0 {
+		parts = append(parts, "removed "+list(removed))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "nothing changed")
+	}
+	if len(unknown) != 0 {
+		parts = append(parts, fmt.Sprintf("unknown %v %v", kind, list(unknown)))
+	}
+	return fmt.Sprintf("%v: %v", ref, strings.Join(parts, "; "))
+}
+
+// labelIssue is the callback for the "label" command.
+func (s *SlackBot) labelIssue(r request, w responder) {
+	changes := parseChanges(r.StringParam("args", ""))
+	if len(changes.Add)+len(changes.Remove) == 0 {
+		w.ReportError(fmt.Errorf("Say which labels to add and remove, eg `+bug -needs-triage`"))
+		return
+	}
+	s.triage(r, w, func(ctx context.Context, client *GitHubIssueBot, ref issueRef) (string, error) {
+		unknown, err := client.ChangeLabels(ctx, ref, changes)
+		if err != nil {
+			return "", trace.Wrap(err)
+		}
+		return describeChanges(ref, changes, unknown, "labels", codeList), nil
+	})
+}
+
+// assignIssue is the callback for the "assign" command. Assignees are slack mentions, @logins or @me.
+func (s *SlackBot) assignIssue(r request, w responder) {
+	changes := parseChanges(r.StringParam("args", ""))
+	if len(changes.Add)+len(changes.Remove) == 0 {
+		w.ReportError(fmt.Errorf("Say who to assign and unassign, eg `@me -@jane`"))
+		return
+	}
+	user := r.Event().User
+	s.triage(r, w, func(ctx context.Context, client *GitHubIssueBot, ref issueRef) (string, error) {
+		// Names that aren't github logins are unknown before github is asked
+		var unknown []string
+		resolve := func(names []string) []string {
+			var logins []string
+			for _, name := range names {
+				login, ok := s.directory.ResolveAssignee(name)
+				if name == "@me" || name == "me" {
+					login, ok = s.directory.Login(user)
+					if !ok {
+						var err error
+						_, login, err = client.CheckToken(ctx)
+						ok = err == nil
+					}
+				}
+				if !ok {
+					unknown = append(unknown, name)
+					continue
+				}
+				logins = append(logins, login)
+			}
+			return logins
+		}
+		logins := IssueChanges{Add: resolve(changes.Add), Remove: resolve(changes.Remove)}
+		notAssignable, err := client.ChangeAssignees(ctx, ref, logins)
+		if err != nil {
+			return "", trace.Wrap(err)
+		}
+		mentions := func(names []string) string {
+			var list []string
+			for _, name := range names {
+				if !strings.HasPrefix(name, "<@") {
+					name = "@" + strings.TrimPrefix(name, "@")
+				}
+				list = append(list, s.directory.ToSlack(name))
+			}
+			return strings.Join(list, ", ")
+		}
+		return describeChanges(ref, logins, append(unknown, notAssignable...), "assignees", mentions), nil
+	})
+}
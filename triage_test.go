@@ -0,0 +1,49 @@
+package main
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type TriageSuite struct{}
+
+var _ = Suite(&TriageSuite{})
+
+func (s *TriageSuite) TestIssueCommandParser(c *C) {
+	bot := &SlackBot{botID: "UBOT"}
+	parse := bot.issueCommandParser("close")
+	testTables := []struct {
+		name string
+		text string
+		ok   bool
+		ref  string
+		args string
+	}{
+		{name: "Issue And Reason", text: "<@UBOT> close teleport#4521 not planned", ok: true, ref: "teleport#4521", args: "not planned"},
+		{name: "Issue Link", text: "close <https://github.com/gravitational/teleport/issues/4521>", ok: true, ref: "<https://github.com/gravitational/teleport/issues/4521>"},
+		{name: "Linked Thread", text: "close completed", ok: true, args: "completed"},
+		{name: "Bare", text: "close", ok: true},
+		{name: "Other Bot", text: "<@UOTHER> close teleport#4521", ok: false},
+		{name: "Other Word", text: "closed teleport#4521", ok: false},
+	}
+	for i, tt := range testTables {
+		comment := Commentf("test #%d (%v)", i+1, tt.name)
+		properties, ok := parse(tt.text)
+		c.Assert(ok, Equals, tt.ok, comment)
+		if ok {
+			c.Assert(properties.StringParam("ref", ""), Equals, tt.ref, comment)
+			c.Assert(properties.StringParam("args", ""), Equals, tt.args, comment)
+		}
+	}
+}
+
+func (s *TriageSuite) TestChanges(c *C) {
+	changes := parseChanges("+bug -needs-triage docs - +")
+	c.Assert(changes, DeepEquals, IssueChanges{Add: []string{"bug", "docs"}, Remove: []string{"needs-triage"}})
+
+	ref := issueRef{Owner: "gravitational", Repo: "teleport", Number: 4521}
+	changes = IssueChanges{Add: []string{"bug", "nope"}, Remove: []string{"needs-triage"}}
+	c.Assert(describeChanges(ref, changes, []string{"nope"}, "labels", codeList), Equals,
+		"gravitational/teleport#4521: added `bug`; removed `needs-triage`; unknown labels `nope`")
+	c.Assert(describeChanges(ref, IssueChanges{Add: []string{"nope"}}, []string{"nope"}, "labels", codeList), Equals,
+		"gravitational/teleport#4521: nothing changed; unknown labels `nope`")
+}
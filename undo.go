@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+const (
+	// undoActionID identifies the "Undo" button on a new issue
+	undoActionID = "undo_issue"
+)
+
+var (
+	// ErrNothingToUndo is reported when someone hasn't filed an issue recently enough to undo it
+	ErrNothingToUndo = errors.New("You haven't filed an issue recently enough to undo it")
+)
+
+// recentIssue is an issue that was filed recently enough to undo.
+type recentIssue struct {
+	user    string
+	created time.Time
+	undoing bool
+}
+
+// recentIssues remembers issues while they can be undone.
+type recentIssues struct {
+	mu     sync.Mutex
+	issues map[issueRef]recentIssue
+}
+
+// add remembers that user just filed ref.
+func (u *recentIssues) add(ref issueRef, user string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.issues == nil {
+		u.issues = make(map[issueRef]recentIssue)
+	}
+	u.issues[ref] = recentIssue{user: user, created: time.Now()}
+}
+
+// start returns the issue user filed most recently within window, or ref if it's given, and marks it
+// as being undone so it isn't undone twice at once. The caller must call finish with it.
+func (u *recentIssues) start(ref *issueRef, user string, window time.Duration) (issueRef, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var found issueRef
+	var latest time.Time
+	for candidate, issue := range u.issues {
+		if time.Since(issue.created) > window {
+			delete(u.issues, candidate)
+			continue
+		}
+		if issue.user != user || issue.undoing || (ref != nil && candidate != *ref) {
+			continue
+		}
+		if issue.created.After(latest) {
+			found, latest = candidate, issue.created
+		}
+	}
+	if latest.IsZero() {
+		return issueRef{}, false
+	}
+	issue := u.issues[found]
+	issue.undoing = true
+	u.issues[found] = issue
+	return found, true
+}
+
+// finish forgets an issue once it's been undone, or lets it be undone again if undoing it failed.
+func (u *recentIssues) finish(ref issueRef, undone bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	issue, ok := u.issues[ref]
+	if !ok {
+		return
+	}
+	if undone {
+		delete(u.issues, ref)
+		return
+	}
+	issue.undoing = false
+	u.issues[ref] = issue
+}
+
+// undoBlocks is a new issue's link with an Undo button, if undo is enabled.
+func (s *SlackBot) undoBlocks(url string, ref issueRef) []slack.Block {
+	text := slack.NewTextBlockObject(slack.MarkdownType, url, false, false)
+	if s.undoWindow <= 0 {
+		return []slack.Block{slack.NewSectionBlock(text, nil, nil)}
+	}
+	undo := slack.NewButtonBlockElement(undoActionID, ref.String(), slack.NewTextBlockObject(slack.PlainTextType, "Undo", false, false))
+	return []slack.Block{slack.NewSectionBlock(text, nil, slack.NewAccessory(undo))}
+}
+
+// undoIssue closes an issue as not planned, or deletes it if the user is an admin of its repo. It
+// returns what was done.
+func (s *SlackBot) undoIssue(ctx context.Context, client *GitHubIssueBot, user string, ref issueRef) (string, error) {
+	subCtx, cancel := context.WithTimeout(ctx, time.Second*TimeoutSeconds)
+	defer cancel()
+	permission, err := client.ViewerPermission(subCtx, ref.Owner, ref.Repo)
+	if err != nil {
+		return "", trace.Wrap(err)
+	}
+	if permission == "ADMIN" {
+		if err := client.DeleteIssue(subCtx, ref); err == nil {
+			s.audit(user, "undo", ref, "deleted")
+			return fmt.Sprintf("Deleted %v", ref), nil
+		}
+		log.Infof("couldn't delete %v, closing it instead: %v", ref, trace.DebugReport(err))
+	}
+	if _, err := client.CloseIssue(subCtx, ref, "NOT_PLANNED"); err != nil {
+		return "", trace.Wrap(err)
+	}
+	s.audit(user, "undo", ref, "closed as not planned")
+	return fmt.Sprintf("Closed %v as not planned", ref), nil
+}
+
+// undoCommand is the callback for the "undo" command, which undoes the issue the user filed last.
+func (s *SlackBot) undoCommand(r request, w responder) {
+	if s.CheckRun(w) {
+		defer s.Done()
+	} else {
+		return
+	}
+	user := r.Event().User
+	client := s.GetGBot(r)
+	if !s.CheckClient(w, client) {
+		return
+	}
+	ref, ok := s.recent.start(nil, user, s.undoWindow)
+	if !ok {
+		w.ReportError(ErrNothingToUndo)
+		return
+	}
+	result, err := s.undoIssue(r.Context(), client, user, ref)
+	s.recent.finish(ref, err == nil)
+	if err != nil {
+		w.ReportError(fmt.Errorf("Couldn't undo %v", ref))
+		log.Infof("undo error: %v", trace.DebugReport(err))
+		return
+	}
+	s.replyInThread(r, w, result)
+}
+
+// undoButton handles the Undo button on a new issue.
+func (s *SlackBot) undoButton(ctx context.Context, payload *interaction, value string) {
+	if !s.Begin() {
+		return
+	}
+	defer s.Done()
+	reply := func(text string, replace bool) {
+		if err := postResponseURL(payload.ResponseURL, slashResponse{Text: text, ReplaceOriginal: replace}); err != nil {
+			log.Errorf("couldn't post to response_url: %v", trace.DebugReport(err))
+		}
+	}
+	ref, ok := parseIssueRef(value, "")
+	if !ok {
+		log.Infof("bad undo button value %q", value)
+		return
+	}
+	client := s.getGBotForUser(ctx, payload.User.ID)
+	if client == nil {
+		reply("*Error:* _You must register first, see `help` command_", false)
+		return
+	}
+	if _, ok := s.recent.start(&ref, payload.User.ID, s.undoWindow); !ok {
+		reply(fmt.Sprintf("*Error:* _Only whoever filed %v can undo it, for %v_", ref, s.undoWindow), false)
+		return
+	}
+	result, err := s.undoIssue(ctx, client, payload.User.ID, ref)
+	s.recent.finish(ref, err == nil)
+	if err != nil {
+		log.Infof("undo error: %v", trace.DebugReport(err))
+		reply(fmt.Sprintf("*Error:* _Couldn't undo %v_", ref), false)
+		return
+	}
+	reply(result, true)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type UndoSuite struct{}
+
+var _ = Suite(&UndoSuite{})
+
+func (s *UndoSuite) TestRecentIssues(c *C) {
+	var recent recentIssues
+	first := issueRef{Owner: "gravitational", Repo: "teleport", Number: 1}
+	second := issueRef{Owner: "gravitational", Repo: "teleport", Number: 2}
+	recent.add(first, "U1")
+	time.Sleep(time.Millisecond)
+	recent.add(second, "U1")
+
+	// Someone else can't undo them
+	_, ok := recent.start(nil, "U2", time.Minute)
+	c.Assert(ok, Equals, false)
+	_, ok = recent.start(&first, "U2", time.Minute)
+	c.Assert(ok, Equals, false)
+
+	// The latest is undone first, and only once
+	ref, ok := recent.start(nil, "U1", time.Minute)
+	c.Assert(ok, Equals, true)
+	c.Assert(ref, Equals, second)
+	_, ok = recent.start(&second, "U1", time.Minute)
+	c.Assert(ok, Equals, false, Commentf("started twice at once"))
+	recent.finish(second, true)
+	_, ok = recent.start(&second, "U1", time.Minute)
+	c.Assert(ok, Equals, false, Commentf("started after it was undone"))
+
+	// If undoing fails it can be tried again
+	ref, ok = recent.start(nil, "U1", time.Minute)
+	c.Assert(ok, Equals, true)
+	c.Assert(ref, Equals, first)
+	recent.finish(first, false)
+	_, ok = recent.start(&first, "U1", time.Minute)
+	c.Assert(ok, Equals, true, Commentf("retried after a failure"))
+	recent.finish(first, false)
+
+	// Nothing can be undone after the window
+	time.Sleep(time.Millisecond)
+	_, ok = recent.start(&first, "U1", time.Nanosecond)
+	c.Assert(ok, Equals, false)
+	_, ok = recent.start(&first, "U1", time.Minute)
+	c.Assert(ok, Equals, false)
+}
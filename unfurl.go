@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gravitational/trace"
+	"github.com/mailgun/log"
+	"github.com/nlopes/slack"
+)
+
+// linkSharedEvent is the Events API link_shared event.
+type linkSharedEvent struct {
+	Channel   string `json:"channel"`
+	User      string `json:"user"`
+	MessageTs string `json:"message_ts"`
+	Links     []struct {
+		Domain string `json:"domain"`
+		URL    string `json:"url"`
+	} `json:"links"`
+}
+
+var (
+	// ErrPrivateRepo is reported when only the --github_token client could look an issue up, and it's in a private repo.
+	ErrPrivateRepo = errors.New("That's in a private repo, register first to see it, see `help` command")
+	// visibilityRegex matches the search qualifiers that pick public or private repos
+	visibilityRegex = regexp.MustCompile(`(?i)^-?is:(?:public|private)$`)
+)
+
+// stateColors are the colors github uses for issue states.
+var stateColors = map[string]string{
+	"OPEN":   "#2cbe4e",
+	"CLOSED": "#cb2431",
+	"MERGED": "#6f42c1",
+}
+
+// gBotFor returns the github client for a slack user, falling back to the --github_token client, and
+// whether it fell back. The fallback can see private repos the user might not, so it's only for public
+// ones. It returns nil if there's neither.
+func (s *SlackBot) gBotFor(ctx context.Context, user string) (*GitHubIssueBot, bool) {
+	if client := s.getGBotForUser(ctx, user); client != nil {
+		return client, false
+	}
+	return s.fallback, s.fallback != nil
+}
+
+// getIssueFor looks up an issue with a client from gBotFor. Issues in private repos aren't returned
+// when it's the fallback client.
+func getIssueFor(ctx context.Context, client *GitHubIssueBot, fellBack bool, ref issueRef) (*IssueDetails, error) {
+	details, err := client.GetIssue(ctx, ref)
+	if err != nil {
+		return nil, trace.Wrap(err)
+	}
+	if fellBack && details.IsPrivate {
+		return nil, trace.Wrap(ErrPrivateRepo)
+	}
+	return details, nil
+}
+
+// publicSearch limits a query to public repos, for searches with the fallback client. Any qualifiers
+// in the query that ask for private repos, or leave out public ones, are dropped.
+func publicSearch(query string) string {
+	terms := []string{"is:public"}
+	for _, term := range strings.Fields(query) {
+		if !visibilityRegex.MatchString(term) {
+			terms = append(terms, term)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// unfurlLinks unfurls the github issue and pull request links in a link_shared event. The sharer's
+// token is used, so private repos are only unfurled by people who can see them; links shared by people
+// who haven't registered are only unfurled if they're public.
+func (s *SlackBot) unfurlLinks(ctx context.Context, event *linkSharedEvent) {
+	if !s.Begin() {
+		return
+	}
+	defer s.Done()
+	client, fellBack := s.gBotFor(ctx, event.User)
+	if client == nil {
+		return
+	}
+
+	subCtx, cancel := context.WithTimeout(ctx, time.Second*TimeoutSeconds)
+	defer cancel()
+	unfurls := make(map[string]slack.Attachment)
+	for _, link := range event.Links {
+		ref, ok := parseIssueURL(link.URL)
+		if !ok {
+			continue
+		}
+		details, err := getIssueFor(subCtx, client, fellBack, ref)
+		if err != nil {
+			log.Infof("couldn't unfurl %v: %v", link.URL, err)
+			continue
+		}
+		unfurls[link.URL] = s.issueAttachment(details)
+	}
+	if len(unfurls) == 0 {
+		return
+	}
+	if _, _, _, err := s.sBot.Client().UnfurlMessage(event.Channel, event.MessageTs, unfurls); err != nil {
+		log.Errorf("couldn't unfurl: %v", trace.DebugReport(err))
+	}
+}
+
+// issueAttachment renders an issue or pull request as a slack attachment.
+func (s *SlackBot) issueAttachment(details *IssueDetails) slack.Attachment {
+	kind := "Issue"
+	if details.IsPullRequest {
+		kind = "Pull request"
+	}
+	var assignees []string
+	for _, login := range details.Assignees {
+		assignees = append(assignees, s.directory.ToSlack("@"+login))
+	}
+	return slack.Attachment{
+		Color:     stateColors[details.State],
+		Title:     fmt.Sprintf("#%v %v", details.Ref.Number, details.Title),
+		TitleLink: details.Url,
+		Fields: []slack.AttachmentField{
+			{Title: "State", Value: strings.Title(strings.ToLower(details.State)), Short: true},
+			{Title: "Comments", Value: fmt.Sprint(details.Comments), Short: true},
+			{Title: "Labels", Value: listOrNone(details.Labels), Short: true},
+			{Title: "Assignees", Value: listOrNone(assignees), Short: true},
+		},
+		Footer:     fmt.Sprintf("%v in %v/%v", kind, details.Ref.Owner, details.Ref.Repo),
+		MarkdownIn: []string{"fields"},
+	}
+}
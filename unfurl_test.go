@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/gravitational/trace"
+	. "gopkg.in/check.v1"
+)
+
+type UnfurlSuite struct{}
+
+var _ = Suite(&UnfurlSuite{})
+
+func (s *UnfurlSuite) TestGetIssueFor(c *C) {
+	testTables := []struct {
+		name     string
+		private  string
+		fallback bool
+		err      error
+	}{
+		{name: "Public With Fallback", private: "false", fallback: true},
+		{name: "Private With Fallback", private: "true", fallback: true, err: ErrPrivateRepo},
+		{name: "Private With Own Token", private: "true", fallback: false},
+	}
+	for i, tt := range testTables {
+		comment := Commentf("test #%d (%v)", i+1, tt.name)
+		var queries []string
+		response := `{"data": {"repository": {"isPrivate": ` + tt.private + `, "issueOrPullRequest": {"__typename": "Issue",
+			"title": "Broken", "state": "OPEN"}}}}`
+		client, done := testGitHub(c, response, &queries)
+		details, err := getIssueFor(context.Background(), client, tt.fallback, issueRef{Owner: "gravitational", Repo: "teleport", Number: 1})
+		done()
+		c.Assert(trace.Unwrap(err), Equals, tt.err, comment)
+		if tt.err == nil {
+			c.Assert(details.Title, Equals, "Broken", comment)
+		}
+	}
+}
+
+func (s *UnfurlSuite) TestGBotFor(c *C) {
+	bot := &SlackBot{}
+	client, fallback := bot.gBotFor(context.Background(), "U1")
+	c.Assert(client, IsNil)
+	c.Assert(fallback, Equals, false)
+
+	bot.fallback = &GitHubIssueBot{}
+	client, fallback = bot.gBotFor(context.Background(), "U1")
+	c.Assert(client, Equals, bot.fallback)
+	c.Assert(fallback, Equals, true)
+
+	registered := &GitHubIssueBot{}
+	bot.gBots.Store("U1", registered)
+	client, fallback = bot.gBotFor(context.Background(), "U1")
+	c.Assert(client, Equals, registered)
+	c.Assert(fallback, Equals, false)
+}
+
+func (s *UnfurlSuite) TestPublicSearch(c *C) {
+	testTables := []struct {
+		name   string
+		query  string
+		public string
+	}{
+		{name: "Unqualified", query: "repo:gravitational/teleport tsh", public: "is:public repo:gravitational/teleport tsh"},
+		{name: "Already Public", query: "is:public tsh", public: "is:public tsh"},
+		{name: "Not Public", query: "-is:public tsh", public: "is:public tsh"},
+		{name: "Private", query: "tsh IS:PRIVATE", public: "is:public tsh"},
+		{name: "Not Private", query: "-is:private tsh is:open", public: "is:public tsh is:open"},
+	}
+	for i, tt := range testTables {
+		c.Assert(publicSearch(tt.query), Equals, tt.public, Commentf("test #%d (%v)", i+1, tt.name))
+	}
+}
+
+func (s *UnfurlSuite) TestFallbackSearchIsPublic(c *C) {
+	var searched []string
+	client, done := testGitHubFunc(c, func(query string, variables map[string]interface{}) string {
+		searched = append(searched, variables["query"].(string))
+		return `{"data": {"search": {"issueCount": 0, "nodes": []}}}`
+	})
+	defer done()
+	responseURL := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer responseURL.Close()
+
+	bot := &SlackBot{running: true, wg: &sync.WaitGroup{}, fallback: client}
+	payload := &interaction{ResponseURL: responseURL.URL}
+	payload.User.ID = "U1"
+	bot.searchMore(context.Background(), payload, `{"q": "-is:public org:gravitational tsh", "a": "Y3Vyc29y"}`)
+	c.Assert(searched, DeepEquals, []string{"is:public org:gravitational tsh"})
+}